@@ -4,62 +4,62 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
-	"github.com/goinbox/golog"
 	"github.com/goinbox/pcontext"
 )
 
-type dbItem struct {
-	config *Config
-	db     *sql.DB
-}
-
-var dbPool = map[string]*dbItem{}
-
 func newDB(config *Config) (*sql.DB, error) {
-	db, err := sql.Open("mysql", config.FormatDSN())
+	err := config.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	connector, err := newConnector(config)
 	if err != nil {
 		return nil, fmt.Errorf("newDB error: %w", err)
 	}
 
+	db := sql.OpenDB(connector)
+
 	db.SetConnMaxLifetime(config.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+	if config.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(config.MaxOpenConns)
+	}
+	if config.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(config.MaxIdleConns)
+	}
 
 	return db, nil
 }
 
-func RegisterDB(key string, config *Config) error {
-	db, err := newDB(config)
-	if err != nil {
-		return err
-	}
+// Client is safe for concurrent use, Begin returns a new Client bound to the transaction
+// and leaves the original one untouched.
+type Client struct {
+	pool *dbPool
+	tx   *sql.Tx
 
-	dbPool[key] = &dbItem{
-		config: config,
-		db:     db,
-	}
+	// savepoint number of a nested transaction, 0 means the outermost transaction,
+	// numbers come from a counter shared by all the clients of tx so that sibling savepoints do not collide
+	savepoint  uint64
+	savepoints *uint64
+	txDone     bool
+	txSpan     Span
 
-	return nil
-}
+	// registry key of the pool, "" for a Client made by NewClient
+	key string
 
-type PrepareQueryFunc func(query string, args ...interface{}) (string, []interface{})
-
-type Client struct {
-	db *sql.DB
-	tx *sql.Tx
+	// reads go to the primary even if the pool has replicas
+	forcePrimary bool
 
 	config *Config
 
 	prepareQuery PrepareQueryFunc
-}
-
-func NewClientFromPool(key string) (*Client, error) {
-	item, ok := dbPool[key]
-	if !ok {
-		return nil, errors.New("DB " + key + " not exist")
-	}
-
-	return newClient(item.db, item.config), nil
+	interceptors []Interceptor
 }
 
 func NewClient(config *Config) (*Client, error) {
@@ -68,13 +68,13 @@ func NewClient(config *Config) (*Client, error) {
 		return nil, fmt.Errorf("newDB error: %w", err)
 	}
 
-	return newClient(db, config), nil
+	return newClient(newDBPool(&dbItem{config: config, db: db}), config), nil
 }
 
-func newClient(db *sql.DB, config *Config) *Client {
+func newClient(pool *dbPool, config *Config) *Client {
 	client := &Client{
-		db: db,
-		tx: nil,
+		pool: pool,
+		tx:   nil,
 
 		config: config,
 	}
@@ -82,6 +82,8 @@ func newClient(db *sql.DB, config *Config) *Client {
 	return client
 }
 
+// SetPrepareQuery sets f to rewrite the query and args of Exec, Query and QueryRow, replacing the previous one,
+// f runs as the innermost interceptor of the chain
 func (c *Client) SetPrepareQuery(f PrepareQueryFunc) *Client {
 	c.prepareQuery = f
 
@@ -89,101 +91,365 @@ func (c *Client) SetPrepareQuery(f PrepareQueryFunc) *Client {
 }
 
 func (c *Client) Exec(ctx pcontext.Context, query string, args ...interface{}) (sql.Result, error) {
-	if c.prepareQuery != nil {
-		query, args = c.prepareQuery(query, args...)
-	}
-	c.log(ctx.Logger(), query, args...)
+	r, err := c.invoke(ctx, OpExec, query, args, func(ctx pcontext.Context, query string, args []interface{}) (*OpResult, error) {
+		item := c.pool.load()
+		config := item.config
+		if c.tx != nil {
+			config = c.config
+		}
 
-	if c.tx != nil {
-		return c.tx.ExecContext(ctx, query, args...)
+		stats := c.newStmtStats(ctx, OpExec, query, args, config)
+		var result sql.Result
+		var err error
+		if c.tx != nil {
+			result, err = c.tx.ExecContext(ctx, query, args...)
+		} else {
+			result, err = item.db.ExecContext(ctx, query, args...)
+		}
+		if err == nil {
+			stats.rowsAffected, _ = result.RowsAffected()
+			stats.lastInsertID, _ = result.LastInsertId()
+		}
+		c.statementDone(ctx, stats.done(err))
+		if err != nil {
+			return nil, c.queryError(ctx, stats)
+		}
+
+		return &OpResult{Result: result}, nil
+	})
+	if r == nil || r.Result == nil {
+		if err == nil {
+			err = errors.New("no result returned by interceptor")
+		}
+		return nil, err
 	}
-	return c.db.ExecContext(ctx, query, args...)
+
+	return r.Result, err
 }
 
 func (c *Client) Query(ctx pcontext.Context, query string, args ...interface{}) (*sql.Rows, error) {
-	if c.prepareQuery != nil {
-		query, args = c.prepareQuery(query, args...)
-	}
-	c.log(ctx.Logger(), query, args...)
+	r, err := c.invoke(ctx, OpQuery, query, args, func(ctx pcontext.Context, query string, args []interface{}) (*OpResult, error) {
+		db, config := c.readDB(ctx)
+		if c.tx != nil {
+			config = c.config
+		}
 
-	if c.tx != nil {
-		return c.tx.QueryContext(ctx, query, args...)
+		stats := c.newStmtStats(ctx, OpQuery, query, args, config)
+		octx := c.observeRows(ctx, stats)
+		var rows *sql.Rows
+		var err error
+		if c.tx != nil {
+			rows, err = c.tx.QueryContext(octx, query, args...)
+		} else {
+			rows, err = db.QueryContext(octx, query, args...)
+		}
+		if err != nil {
+			c.statementDone(ctx, stats.done(err))
+			return nil, c.queryError(ctx, stats)
+		}
+
+		return &OpResult{Rows: rows}, nil
+	})
+	if r == nil || r.Rows == nil {
+		if err == nil {
+			err = errors.New("no rows returned by interceptor")
+		}
+		return nil, err
 	}
-	return c.db.QueryContext(ctx, query, args...)
+
+	return r.Rows, err
 }
 
 func (c *Client) QueryRow(ctx pcontext.Context, query string, args ...interface{}) *sql.Row {
-	if c.prepareQuery != nil {
-		query, args = c.prepareQuery(query, args...)
+	r, err := c.invoke(ctx, OpQueryRow, query, args, func(ctx pcontext.Context, query string, args []interface{}) (*OpResult, error) {
+		db, config := c.readDB(ctx)
+		if c.tx != nil {
+			config = c.config
+		}
+
+		stats := c.newStmtStats(ctx, OpQueryRow, query, args, config)
+		octx := c.observeRows(ctx, stats)
+		var row *sql.Row
+		if c.tx != nil {
+			row = c.tx.QueryRowContext(octx, query, args...)
+		} else {
+			row = db.QueryRowContext(octx, query, args...)
+		}
+		if row.Err() != nil {
+			c.statementDone(ctx, stats.done(row.Err()))
+			err := c.queryError(ctx, stats)
+			return &OpResult{Row: errRow(err)}, err
+		}
+
+		return &OpResult{Row: row}, nil
+	})
+	if r == nil || r.Row == nil {
+		if err == nil {
+			err = errors.New("no row returned by interceptor")
+		}
+		return errRow(err)
 	}
-	c.log(ctx.Logger(), query, args...)
 
-	if c.tx != nil {
-		return c.tx.QueryRowContext(ctx, query, args...)
+	return r.Row
+}
+
+type TxOptions struct {
+	Isolation          sql.IsolationLevel
+	ReadOnly           bool
+	ConsistentSnapshot bool
+}
+
+func (o *TxOptions) sqlTxOptions() *sql.TxOptions {
+	return &sql.TxOptions{
+		Isolation: o.Isolation,
+		ReadOnly:  o.ReadOnly,
 	}
-	return c.db.QueryRowContext(ctx, query, args...)
 }
 
-func (c *Client) Begin(ctx pcontext.Context) error {
+// logQuery returns the BEGIN log line with the options appended, e.g.
+// BEGIN ISOLATION LEVEL READ COMMITTED, READ ONLY
+func (o *TxOptions) logQuery() string {
+	var items []string
+	if o.Isolation != sql.LevelDefault {
+		items = append(items, "ISOLATION LEVEL "+strings.ToUpper(o.Isolation.String()))
+	}
+	if o.ReadOnly {
+		items = append(items, "READ ONLY")
+	}
+	if o.ConsistentSnapshot {
+		items = append(items, "WITH CONSISTENT SNAPSHOT")
+	}
+
+	if len(items) == 0 {
+		return "BEGIN"
+	}
+	return "BEGIN " + strings.Join(items, ", ")
+}
+
+func (c *Client) Begin(ctx pcontext.Context) (*Client, error) {
+	return c.BeginTx(ctx, nil)
+}
+
+// BeginTx starts a transaction bound to ctx and returns a Client running everything in it,
+// the transaction will be rolled back by database/sql if ctx is done before Commit.
+// ConsistentSnapshot restarts the transaction with START TRANSACTION WITH CONSISTENT SNAPSHOT, which uses the
+// session isolation level, so it can not be combined with an explicit Isolation.
+// Calling BeginTx on a transaction Client creates a savepoint instead, opts only apply to the outermost level.
+func (c *Client) BeginTx(ctx pcontext.Context, opts *TxOptions) (*Client, error) {
 	if c.tx != nil {
-		return errors.New("already in trans")
+		return c.beginSavepoint(ctx)
 	}
 
-	tx, err := c.db.Begin()
-	if err != nil {
-		return err
+	if opts == nil {
+		opts = new(TxOptions)
+	}
+	if opts.ConsistentSnapshot && opts.Isolation != sql.LevelDefault {
+		return nil, errors.New("consistent snapshot can not be used with isolation level " + opts.Isolation.String())
+	}
+
+	r, err := c.invoke(ctx, OpBegin, opts.logQuery(), nil, func(ctx pcontext.Context, query string, args []interface{}) (*OpResult, error) {
+		start := time.Now()
+		item := c.pool.load()
+		span := c.startSpan(ctx, SpanNameTransaction, item.config, nil)
+		tx, err := item.db.BeginTx(ctx, opts.sqlTxOptions())
+		if err != nil {
+			c.logTrans(ctx, query, start, err)
+			endSpan(span, err)
+			return nil, err
+		}
+
+		if opts.ConsistentSnapshot {
+			snapshotQuery := "START TRANSACTION WITH CONSISTENT SNAPSHOT"
+			if opts.ReadOnly {
+				snapshotQuery += ", READ ONLY"
+			}
+			_, err = tx.ExecContext(ctx, snapshotQuery)
+			if err != nil {
+				c.logTrans(ctx, snapshotQuery, start, err)
+				_ = tx.Rollback()
+				endSpan(span, err)
+				return nil, err
+			}
+		}
+		c.logTrans(ctx, query, start, nil)
+
+		client := c.txClient(tx, 0)
+		client.savepoints = new(uint64)
+		client.txSpan = span
+
+		return &OpResult{Tx: client}, nil
+	})
+
+	return txFromOpResult(r, err)
+}
+
+func (c *Client) txClient(tx *sql.Tx, savepoint uint64) *Client {
+	client := *c
+	client.tx = tx
+	client.savepoint = savepoint
+	client.txDone = false
+
+	return &client
+}
+
+func (c *Client) beginSavepoint(ctx pcontext.Context) (*Client, error) {
+	if c.txDone {
+		return nil, sql.ErrTxDone
 	}
 
-	c.log(ctx.Logger(), "BEGIN")
-	c.tx = tx
+	savepoint := atomic.AddUint64(c.savepoints, 1)
+	r, err := c.invoke(ctx, OpBegin, "SAVEPOINT "+savepointName(savepoint), nil, func(ctx pcontext.Context, query string, args []interface{}) (*OpResult, error) {
+		err := c.execSavepoint(ctx, query)
+		if err != nil {
+			return nil, err
+		}
 
-	return nil
+		return &OpResult{Tx: c.txClient(c.tx, savepoint)}, nil
+	})
+
+	return txFromOpResult(r, err)
 }
 
-func (c *Client) Commit(ctx pcontext.Context) error {
-	defer func() {
-		c.tx = nil
-	}()
+func txFromOpResult(r *OpResult, err error) (*Client, error) {
+	if err != nil {
+		return nil, err
+	}
+	if r == nil || r.Tx == nil {
+		return nil, errors.New("no trans returned by interceptor")
+	}
 
-	if c.tx != nil {
-		c.log(ctx.Logger(), "COMMIT")
+	return r.Tx, nil
+}
 
-		return c.tx.Commit()
+// Commit commits the transaction, or releases the savepoint if c is a nested transaction
+func (c *Client) Commit(ctx pcontext.Context) error {
+	if c.tx == nil {
+		return errors.New("not in trans")
+	}
+	if c.txDone {
+		return sql.ErrTxDone
 	}
 
-	return errors.New("not in trans")
+	if c.savepoint > 0 {
+		_, err := c.invoke(ctx, OpCommit, "RELEASE SAVEPOINT "+savepointName(c.savepoint), nil, c.savepointHandler)
+		return err
+	}
+
+	_, err := c.invoke(ctx, OpCommit, "COMMIT", nil, func(ctx pcontext.Context, query string, args []interface{}) (*OpResult, error) {
+		start := time.Now()
+		if ctx.Err() != nil {
+			_ = c.tx.Rollback()
+			c.txDone = true
+			c.logTrans(ctx, query, start, ctx.Err())
+			c.endTxSpan("rollback", ctx.Err())
+
+			return nil, ctx.Err()
+		}
+
+		err := txDoneError(ctx, c.tx.Commit())
+		c.txDone = true
+		c.logTrans(ctx, query, start, err)
+		c.endTxSpan("commit", err)
+
+		return nil, err
+	})
+
+	return err
 }
 
+// Rollback rolls back the transaction, or rolls back to the savepoint if c is a nested transaction
 func (c *Client) Rollback(ctx pcontext.Context) error {
+	if c.tx == nil {
+		return errors.New("not in trans")
+	}
+	if c.txDone {
+		return sql.ErrTxDone
+	}
+
+	if c.savepoint > 0 {
+		_, err := c.invoke(ctx, OpRollback, "ROLLBACK TO SAVEPOINT "+savepointName(c.savepoint), nil, c.savepointHandler)
+		return err
+	}
+
+	_, err := c.invoke(ctx, OpRollback, "ROLLBACK", nil, func(ctx pcontext.Context, query string, args []interface{}) (*OpResult, error) {
+		start := time.Now()
+		err := txDoneError(ctx, c.tx.Rollback())
+		c.txDone = true
+		c.logTrans(ctx, query, start, err)
+		c.endTxSpan("rollback", err)
+
+		return nil, err
+	})
+
+	return err
+}
+
+// savepointHandler ends a nested transaction, c is only marked done once the statement has run,
+// so an interceptor returning without calling next leaves it open for a later Rollback
+func (c *Client) savepointHandler(ctx pcontext.Context, query string, args []interface{}) (*OpResult, error) {
+	err := c.execSavepoint(ctx, query)
+	c.txDone = true
+
+	return nil, err
+}
+
+func (c *Client) execSavepoint(ctx pcontext.Context, query string) error {
+	start := time.Now()
+	_, err := c.tx.ExecContext(ctx, query)
+	c.logTrans(ctx, query, start, err)
+
+	return txDoneError(ctx, err)
+}
+
+func savepointName(savepoint uint64) string {
+	return "sp_" + strconv.FormatUint(savepoint, 10)
+}
+
+type TransactionFunc func(tx *Client) error
+
+// WithTransaction runs f in a transaction, commits if f returns nil and rolls back if f returns an error or panics,
+// the panic is raised again after rollback
+func (c *Client) WithTransaction(ctx pcontext.Context, f TransactionFunc) error {
+	return c.WithTransactionOptions(ctx, nil, f)
+}
+
+func (c *Client) WithTransactionOptions(ctx pcontext.Context, opts *TxOptions, f TransactionFunc) error {
+	tx, err := c.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+
 	defer func() {
-		c.tx = nil
+		if p := recover(); p != nil {
+			_ = tx.Rollback(ctx)
+			panic(p)
+		}
 	}()
 
-	if c.tx != nil {
-		c.log(ctx.Logger(), "ROLLBACK")
+	err = f(tx)
+	if err != nil {
+		rerr := tx.Rollback(ctx)
+		if rerr != nil {
+			return fmt.Errorf("%w, rollback error: %w", err, rerr)
+		}
+		return err
+	}
 
-		return c.tx.Rollback()
+	err = tx.Commit(ctx)
+	if err != nil && !tx.txDone {
+		// an interceptor refused the commit, release the connection instead of leaking the transaction
+		_ = tx.Rollback(ctx)
 	}
 
-	return errors.New("not in trans")
+	return err
 }
 
-func (c *Client) log(logger golog.Logger, query string, args ...interface{}) {
-	query = strings.Replace(query, "?", "%s", -1)
-	vs := make([]interface{}, len(args))
-
-	for i, v := range args {
-		s := fmt.Sprint(v)
-		switch v.(type) {
-		case string:
-			vs[i] = "'" + s + "'"
-		default:
-			vs[i] = s
-		}
+// txDoneError reports ctx.Err() instead of sql.ErrTxDone when the transaction
+// has already been rolled back because ctx is done
+func txDoneError(ctx pcontext.Context, err error) error {
+	if errors.Is(err, sql.ErrTxDone) && ctx.Err() != nil {
+		return ctx.Err()
 	}
 
-	logger.Info("run sql", &golog.Field{
-		Key:   c.config.LogFieldKeySql,
-		Value: fmt.Sprintf(query, vs...),
-	})
+	return err
 }
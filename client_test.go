@@ -2,6 +2,7 @@ package mysql
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"strconv"
 	"testing"
@@ -90,30 +91,32 @@ func TestClientQueryRow(t *testing.T) {
 }
 
 func TestClientTrans(t *testing.T) {
-	_ = client.Begin(ctx)
+	tx, _ := client.Begin(ctx)
 
-	_, err := client.Exec(ctx, "insert into demo (name) values ('ab')")
-	_, err = client.Exec(ctx, "insert into id_gen (name) values ('demo')")
+	_, err := tx.Exec(ctx, "insert into demo (name) values ('ab')")
+	_, err = tx.Exec(ctx, "insert into id_gen (name) values ('demo')")
 
-	_ = client.Commit(ctx)
+	_ = tx.Commit(ctx)
 
-	// err = client.Rollback()
+	// err = tx.Rollback()
 	t.Log(err)
 
-	_ = client.Begin(ctx)
-	_, _ = client.Exec(ctx, "update id_gen set max_id = 100")
-	r, err := client.Exec(ctx, "update demo set name = 'abc' where id = 0")
+	tx, _ = client.Begin(ctx)
+	_, _ = tx.Exec(ctx, "update id_gen set max_id = 100")
+	r, err := tx.Exec(ctx, "update demo set name = 'abc' where id = 0")
 	t.Log(err)
 	n, err := r.RowsAffected()
 	t.Log(n, err)
 	if n == 0 {
-		_ = client.Rollback(ctx)
+		_ = tx.Rollback(ctx)
 	}
 }
 
 func TestClientPool(t *testing.T) {
 	key := "test"
-	_ = RegisterDB(key, NewDefaultConfig("root", "123", "127.0.0.1", "gobox-demo", 3306))
+	if err := RegisterDB(key, NewDefaultConfig("root", "123", "127.0.0.1", "gobox-demo", 3306)); err != nil {
+		t.Fatal(err)
+	}
 
 	client, _ = NewClientFromPool(key)
 
@@ -124,3 +127,167 @@ func TestClientPool(t *testing.T) {
 	_, err = client.Exec(ctx, "update demo set status = 1")
 	t.Log(err)
 }
+
+func TestClientTransOptions(t *testing.T) {
+	tx, err := client.BeginTx(ctx, &TxOptions{
+		Isolation: sql.LevelReadCommitted,
+		ReadOnly:  true,
+	})
+	t.Log(err)
+
+	row := tx.QueryRow(ctx, "SELECT count(*) FROM demo")
+	var total int64
+	t.Log(row.Scan(&total), total)
+	t.Log(tx.Commit(ctx))
+
+	tx, err = client.BeginTx(ctx, &TxOptions{
+		ConsistentSnapshot: true,
+	})
+	t.Log(err)
+	t.Log(tx.Rollback(ctx))
+}
+
+func TestClientWithTransaction(t *testing.T) {
+	err := client.WithTransaction(ctx, func(tx *Client) error {
+		_, err := tx.Exec(ctx, "insert into demo (name) values ('with_trans')")
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, "update demo set status = 1 where name = 'with_trans'")
+		return err
+	})
+	t.Log(err)
+
+	err = client.WithTransaction(ctx, func(tx *Client) error {
+		_, _ = tx.Exec(ctx, "insert into demo (name) values ('with_trans')")
+		return errors.New("rollback")
+	})
+	t.Log(err)
+}
+
+func TestClientNestedTrans(t *testing.T) {
+	tx, _ := client.Begin(ctx)
+	_, _ = tx.Exec(ctx, "insert into demo (name) values ('outer')")
+
+	inner, _ := tx.Begin(ctx)
+	_, _ = inner.Exec(ctx, "insert into demo (name) values ('inner_rollback')")
+	t.Log(inner.Rollback(ctx))
+
+	err := tx.WithTransaction(ctx, func(tx *Client) error {
+		_, err := tx.Exec(ctx, "insert into demo (name) values ('inner_commit')")
+		return err
+	})
+	t.Log(err)
+
+	t.Log(tx.Commit(ctx))
+}
+
+func TestClientConcurrentTrans(t *testing.T) {
+	tx, _ := client.Begin(ctx)
+	_, _ = tx.Exec(ctx, "insert into demo (name) values ('in_trans')")
+
+	// client is not bound to tx, the count runs outside the transaction
+	var total int64
+	err := client.QueryRow(ctx, "SELECT count(*) FROM demo WHERE name = 'in_trans'").Scan(&total)
+	t.Log(err, total)
+
+	t.Log(tx.Rollback(ctx))
+}
+
+func TestClientSlowQuery(t *testing.T) {
+	client.config.SlowQueryThreshold = time.Millisecond * 10
+	defer func() {
+		client.config.SlowQueryThreshold = DefaultSlowQueryThreshold
+	}()
+
+	_, err := client.Exec(ctx, "SELECT SLEEP(0.02)")
+	t.Log(err)
+
+	rows, err := client.Query(ctx, "SELECT id, SLEEP(0.01) FROM demo LIMIT 2")
+	if err == nil {
+		for rows.Next() {
+		}
+		_ = rows.Close()
+	}
+	t.Log(err)
+}
+
+func TestClientLogLevel(t *testing.T) {
+	config := NewDefaultConfig("root", "123", "127.0.0.1", "gobox-demo", 3306)
+	config.LogLevelStatement = LogLevelDebug
+	config.LogLevelTrans = LogLevelNotice
+	client, _ := NewClient(config)
+
+	_ = client.WithTransaction(ctx, func(tx *Client) error {
+		_, err := tx.Exec(ctx, "update demo set status = 1 where id = 1")
+		return err
+	})
+
+	config.LogErrorsOnly = true
+	_, err := client.Exec(ctx, "update demo set status = 1 where id = 1")
+	t.Log(err)
+	_, err = client.Exec(ctx, "update not_exist_table set status = 1")
+	t.Log(err)
+}
+
+func TestClientSavepointNames(t *testing.T) {
+	var queries []string
+	parent := &Client{config: NewDefaultConfig("root", "123", "127.0.0.1", "gobox-demo", 3306)}
+	parent.Use(func(ctx pcontext.Context, op Operation, query string, args []interface{}, next Handler) (*OpResult, error) {
+		queries = append(queries, query)
+		return &OpResult{Tx: new(Client)}, nil
+	})
+	parent = parent.txClient(new(sql.Tx), 0)
+	parent.savepoints = new(uint64)
+
+	_, _ = parent.Begin(ctx)
+	_, _ = parent.Begin(ctx)
+	_, _ = parent.txClient(parent.tx, 1).Begin(ctx)
+
+	expect := "[SAVEPOINT sp_1 SAVEPOINT sp_2 SAVEPOINT sp_3]"
+	if fmt.Sprint(queries) != expect {
+		t.Error("savepoint names should be unique in a trans", queries)
+	}
+}
+
+func TestClientWithTransactionRollbackError(t *testing.T) {
+	client := &Client{config: NewDefaultConfig("root", "123", "127.0.0.1", "gobox-demo", 3306)}
+	client.Use(func(ctx pcontext.Context, op Operation, query string, args []interface{}, next Handler) (*OpResult, error) {
+		// a trans already done, so that its rollback fails with sql.ErrTxDone
+		return &OpResult{Tx: &Client{tx: new(sql.Tx), txDone: true}}, nil
+	})
+
+	errRun := errors.New("run error")
+	err := client.WithTransaction(ctx, func(tx *Client) error {
+		return errRun
+	})
+	if !errors.Is(err, errRun) || !errors.Is(err, sql.ErrTxDone) {
+		t.Error("error should wrap both the run and the rollback error", err)
+	}
+}
+
+func TestClientWithTransactionInterceptedCommit(t *testing.T) {
+	connector := new(fakeConnector)
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	config := NewDefaultConfig("root", "123", "127.0.0.1", "gobox-demo", 3306)
+	client := newClient(newDBPool(&dbItem{config: config, db: db}), config)
+	errRefused := errors.New("commit refused")
+	client.Use(func(ctx pcontext.Context, op Operation, query string, args []interface{}, next Handler) (*OpResult, error) {
+		if op == OpCommit {
+			return nil, errRefused
+		}
+		return next(ctx, query, args)
+	})
+
+	err := client.WithTransaction(ctx, func(tx *Client) error {
+		return nil
+	})
+	if !errors.Is(err, errRefused) {
+		t.Error("error should be the interceptor error", err)
+	}
+	if connector.rollbacks != 1 || db.Stats().InUse != 0 {
+		t.Error("trans should be rolled back and its connection released", connector.rollbacks, db.Stats().InUse)
+	}
+}
@@ -0,0 +1,188 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+	"math/rand"
+	"sync/atomic"
+
+	"github.com/goinbox/pcontext"
+)
+
+const (
+	ReplicaBalanceRoundRobin = "round_robin"
+	ReplicaBalanceWeighted   = "weighted"
+)
+
+type ReplicaConfig struct {
+	*Config
+
+	// only used by ReplicaBalanceWeighted, <= 0 is treated as 1
+	Weight int
+}
+
+// ClusterConfig describes one primary and its replicas,
+// Query and QueryRow outside transactions are routed to replicas, everything else to the primary
+type ClusterConfig struct {
+	Primary  *Config
+	Replicas []*ReplicaConfig
+
+	Balance string
+}
+
+type replica struct {
+	config *Config
+	weight int
+	db     *sql.DB
+
+	// set by the health checker, unhealthy replicas are out of rotation
+	unhealthy int32
+}
+
+func (r *replica) healthy() bool {
+	return atomic.LoadInt32(&r.unhealthy) == 0
+}
+
+// setHealthy returns whether the state is changed
+func (r *replica) setHealthy(healthy bool) bool {
+	if healthy {
+		return atomic.CompareAndSwapInt32(&r.unhealthy, 1, 0)
+	}
+
+	return atomic.CompareAndSwapInt32(&r.unhealthy, 0, 1)
+}
+
+type replicaSet struct {
+	balance  string
+	replicas []*replica
+
+	counter uint64
+}
+
+func newReplicaSet(config *ClusterConfig) (*replicaSet, error) {
+	balance := config.Balance
+	switch balance {
+	case "":
+		balance = ReplicaBalanceRoundRobin
+	case ReplicaBalanceRoundRobin, ReplicaBalanceWeighted:
+	default:
+		return nil, errors.New("unknown replica balance " + balance)
+	}
+
+	set := &replicaSet{
+		balance: balance,
+	}
+	for _, rc := range config.Replicas {
+		db, err := newDB(rc.Config)
+		if err != nil {
+			set.close()
+			return nil, err
+		}
+
+		weight := rc.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		set.replicas = append(set.replicas, &replica{
+			config: rc.Config,
+			weight: weight,
+			db:     db,
+		})
+	}
+
+	return set, nil
+}
+
+// pick returns nil when there is no healthy replica, the caller falls back to the primary
+func (s *replicaSet) pick() *replica {
+	l := uint64(len(s.replicas))
+	if l == 0 {
+		return nil
+	}
+
+	if s.balance == ReplicaBalanceWeighted {
+		return s.pickWeighted()
+	}
+
+	i := atomic.AddUint64(&s.counter, 1) - 1
+	for j := uint64(0); j < l; j++ {
+		r := s.replicas[(i+j)%l]
+		if r.healthy() {
+			return r
+		}
+	}
+
+	return nil
+}
+
+func (s *replicaSet) pickWeighted() *replica {
+	total := 0
+	for _, r := range s.replicas {
+		if r.healthy() {
+			total += r.weight
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+
+	n := rand.Intn(total)
+	for _, r := range s.replicas {
+		if !r.healthy() {
+			continue
+		}
+		n -= r.weight
+		if n < 0 {
+			return r
+		}
+	}
+
+	return nil
+}
+
+func (s *replicaSet) close() error {
+	var err error
+	for _, r := range s.replicas {
+		if e := r.db.Close(); e != nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+type primaryContextKey struct{}
+
+// WithPrimary returns a context forcing reads made with it to the primary, e.g. to read your own writes
+func WithPrimary(ctx pcontext.Context) pcontext.Context {
+	return withValue(ctx, primaryContextKey{}, true)
+}
+
+func primaryForced(ctx pcontext.Context) bool {
+	v, _ := ctx.Value(primaryContextKey{}).(bool)
+
+	return v
+}
+
+// Primary returns a Client sending all its reads to the primary
+func (c *Client) Primary() *Client {
+	client := *c
+	client.forcePrimary = true
+
+	return &client
+}
+
+// readDB returns the DB for reads outside transactions and its config
+func (c *Client) readDB(ctx pcontext.Context) (*sql.DB, *Config) {
+	item := c.pool.load()
+	if item.replicas == nil || c.forcePrimary || primaryForced(ctx) {
+		return item.db, item.config
+	}
+
+	r := item.replicas.pick()
+	if r == nil {
+		return item.db, item.config
+	}
+
+	return r.db, r.config
+}
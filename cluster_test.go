@@ -0,0 +1,121 @@
+package mysql
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/goinbox/pcontext"
+)
+
+func clusterConfig(balance string) *ClusterConfig {
+	return &ClusterConfig{
+		Primary: NewDefaultConfig("root", "123", "127.0.0.1", "gobox-demo", 3306),
+		Replicas: []*ReplicaConfig{
+			{Config: NewDefaultConfig("root", "123", "127.0.0.2", "gobox-demo", 3306), Weight: 1},
+			{Config: NewDefaultConfig("root", "123", "127.0.0.3", "gobox-demo", 3306), Weight: 3},
+		},
+		Balance: balance,
+	}
+}
+
+func TestReplicaSetPick(t *testing.T) {
+	set, err := newReplicaSet(clusterConfig(ReplicaBalanceRoundRobin))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer set.close()
+
+	for i := 0; i < 4; i++ {
+		r := set.pick()
+		if r != set.replicas[i%2] {
+			t.Error("round robin pick error", i, r.config.Addr)
+		}
+	}
+
+	set, err = newReplicaSet(clusterConfig(ReplicaBalanceWeighted))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer set.close()
+
+	cnts := map[*replica]int{}
+	for i := 0; i < 4000; i++ {
+		cnts[set.pick()]++
+	}
+	if cnts[set.replicas[1]] < cnts[set.replicas[0]]*2 {
+		t.Error("weighted pick error", cnts[set.replicas[0]], cnts[set.replicas[1]])
+	}
+
+	config := clusterConfig("")
+	set, err = newReplicaSet(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer set.close()
+	if set.balance != ReplicaBalanceRoundRobin || config.Balance != "" {
+		t.Error("default balance error", set.balance, config.Balance)
+	}
+
+	if _, err = newReplicaSet(clusterConfig("random")); err == nil {
+		t.Error("unknown balance should fail")
+	}
+}
+
+func readDB(client *Client, ctx pcontext.Context) *sql.DB {
+	db, _ := client.readDB(ctx)
+
+	return db
+}
+
+func TestClusterClient(t *testing.T) {
+	key := "cluster"
+	_ = RegisterClusterDB(key, clusterConfig(ReplicaBalanceRoundRobin))
+
+	client, err := NewClientFromPool(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	primary := client.pool.load().db
+	if readDB(client, ctx) == primary {
+		t.Error("read should go to replica")
+	}
+	if readDB(client.Primary(), ctx) != primary {
+		t.Error("Primary client read should go to primary")
+	}
+	if readDB(client, WithPrimary(ctx)) != primary {
+		t.Error("WithPrimary context read should go to primary")
+	}
+
+	row := client.QueryRow(ctx, "SELECT count(*) FROM demo")
+	var total int64
+	t.Log(row.Scan(&total), total)
+}
+
+func TestReplicaSetPickHealthy(t *testing.T) {
+	for _, balance := range []string{ReplicaBalanceRoundRobin, ReplicaBalanceWeighted} {
+		set, err := newReplicaSet(clusterConfig(balance))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		set.replicas[1].setHealthy(false)
+		for i := 0; i < 10; i++ {
+			if r := set.pick(); r != set.replicas[0] {
+				t.Error(balance, "unhealthy replica picked")
+			}
+		}
+
+		set.replicas[0].setHealthy(false)
+		if r := set.pick(); r != nil {
+			t.Error(balance, "no replica should be picked")
+		}
+
+		set.replicas[1].setHealthy(true)
+		if r := set.pick(); r != set.replicas[1] {
+			t.Error(balance, "recovered replica not picked")
+		}
+
+		_ = set.close()
+	}
+}
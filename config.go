@@ -1,7 +1,9 @@
 package mysql
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/go-sql-driver/mysql"
@@ -12,17 +14,82 @@ const (
 	DefaultReadTimeout     = 10 * time.Second
 	DefaultWriteTimeout    = 10 * time.Second
 	DefaultConnMaxLifetime = 30 * time.Second
+	DefaultConnMaxIdleTime = 10 * time.Second
+	DefaultMaxOpenConns    = 50
+	DefaultMaxIdleConns    = 10
 
-	DefaultLogFieldKeyAddr = "mysql"
-	DefaultLogFieldKeySql  = "sql"
+	DefaultSlowQueryThreshold = time.Second
+
+	DefaultLogFieldKeyAddr     = "mysql"
+	DefaultLogFieldKeySql      = "sql"
+	DefaultLogFieldKeyDuration = "duration"
+	DefaultLogFieldKeyRows     = "rows"
+	DefaultLogFieldKeyArgs     = "args"
+	DefaultLogFieldKeyError    = "error"
+
+	DefaultLogFieldKeyErrorNumber  = "error_number"
+	DefaultLogFieldKeyLastInsertID = "last_insert_id"
+	DefaultLogFieldKeyInTrans      = "in_trans"
+	DefaultLogFieldKeyPool         = "pool"
+
+	DefaultLogSampleRate = 1
 )
 
 type Config struct {
 	*mysql.Config
 
 	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// <= 0 keeps the database/sql defaults: unlimited open connections and 2 idle connections
+	MaxOpenConns int
+	MaxIdleConns int
+
+	// statements running longer are logged at warning level, 0 disables the slow query log
+	SlowQueryThreshold time.Duration
+
+	// row alias used by Dao.Upsert to refer to inserted values, e.g. new, on MySQL 8.0.19+ where VALUES() is deprecated,
+	// "" uses VALUES(col) for older servers and MariaDB
+	UpsertRowAlias string
+
+	// LogLevelError can not be LogLevelOff, failures are always logged
+	LogLevelStatement LogLevel
+	LogLevelTrans     LogLevel
+	LogLevelError     LogLevel
+
+	// fraction of successful statements logged, <= 0 or >= 1 logs all of them, failures are always logged
+	LogSampleRate float64
+	// only log failures, LogSampleRate is ignored
+	LogErrorsOnly bool
+
+	// args bound to these columns by SqlQueryBuilder are logged as LogRedactedValue, e.g. password
+	LogRedactColumns []string
+	// string args matching any of the patterns are logged as LogRedactedValue
+	LogRedactPatterns []*regexp.Regexp
+	LogRedactFunc     LogRedactFunc
+
+	// max length of each string or binary arg and of the whole sql in logs, 0 means unlimited
+	LogMaxArgLength int
+	LogMaxSqlLength int
+
+	// log args in the LogFieldKeyArgs field instead of interpolating them into the sql
+	LogArgsAsField bool
+
+	LogFieldKeySql      string
+	LogFieldKeyDuration string
+	LogFieldKeyRows     string
+	LogFieldKeyArgs     string
+	LogFieldKeyError    string
 
-	LogFieldKeySql string
+	LogFieldKeyErrorNumber  string
+	LogFieldKeyLastInsertID string
+	LogFieldKeyInTrans      string
+	LogFieldKeyAddr         string
+	LogFieldKeyPool         string
+
+	MetricsCollector MetricsCollector
+	Tracer           Tracer
+	DigestAggregator *DigestAggregator
 }
 
 func NewDefaultConfig(user, pass, host, dbname string, port int) *Config {
@@ -49,7 +116,44 @@ func NewDefaultConfig(user, pass, host, dbname string, port int) *Config {
 		Config: config,
 
 		ConnMaxLifetime: DefaultConnMaxLifetime,
+		ConnMaxIdleTime: DefaultConnMaxIdleTime,
+		MaxOpenConns:    DefaultMaxOpenConns,
+		MaxIdleConns:    DefaultMaxIdleConns,
+
+		SlowQueryThreshold: DefaultSlowQueryThreshold,
+
+		LogLevelStatement: LogLevelInfo,
+		LogLevelTrans:     LogLevelInfo,
+		LogLevelError:     LogLevelError,
+		LogSampleRate:     DefaultLogSampleRate,
 
-		LogFieldKeySql: DefaultLogFieldKeySql,
+		LogFieldKeySql:      DefaultLogFieldKeySql,
+		LogFieldKeyDuration: DefaultLogFieldKeyDuration,
+		LogFieldKeyRows:     DefaultLogFieldKeyRows,
+		LogFieldKeyArgs:     DefaultLogFieldKeyArgs,
+		LogFieldKeyError:    DefaultLogFieldKeyError,
+
+		LogFieldKeyErrorNumber:  DefaultLogFieldKeyErrorNumber,
+		LogFieldKeyLastInsertID: DefaultLogFieldKeyLastInsertID,
+		LogFieldKeyInTrans:      DefaultLogFieldKeyInTrans,
+		LogFieldKeyAddr:         DefaultLogFieldKeyAddr,
+		LogFieldKeyPool:         DefaultLogFieldKeyPool,
 	}
 }
+
+func (c *Config) Validate() error {
+	if c.ConnMaxLifetime < 0 {
+		return errors.New("ConnMaxLifetime can not be negative")
+	}
+	if c.ConnMaxIdleTime < 0 {
+		return errors.New("ConnMaxIdleTime can not be negative")
+	}
+	if c.MaxOpenConns > 0 && c.MaxIdleConns > c.MaxOpenConns {
+		return fmt.Errorf("MaxIdleConns %d is greater than MaxOpenConns %d", c.MaxIdleConns, c.MaxOpenConns)
+	}
+	if c.LogLevelError == LogLevelOff {
+		return errors.New("LogLevelError can not be off")
+	}
+
+	return nil
+}
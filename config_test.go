@@ -0,0 +1,31 @@
+package mysql
+
+import (
+	"testing"
+)
+
+func TestConfigValidate(t *testing.T) {
+	config := NewDefaultConfig("root", "123", "127.0.0.1", "gobox-demo", 3306)
+	if err := config.Validate(); err != nil {
+		t.Error("default config should be valid", err)
+	}
+
+	config.MaxIdleConns = config.MaxOpenConns + 1
+	if err := config.Validate(); err == nil {
+		t.Error("idle conns greater than open conns should be invalid")
+	}
+
+	config.MaxOpenConns = 0
+	if err := config.Validate(); err != nil {
+		t.Error("unlimited open conns should be valid", err)
+	}
+
+	config.LogLevelError = LogLevelOff
+	if err := config.Validate(); err == nil {
+		t.Error("error log level off should be invalid")
+	}
+
+	if err := RegisterDB("invalid", &Config{Config: config.Config, MaxOpenConns: 1, MaxIdleConns: 2}); err == nil {
+		t.Error("register invalid config should fail")
+	}
+}
@@ -0,0 +1,57 @@
+package mysql
+
+import (
+	"github.com/goinbox/pcontext"
+)
+
+// valueContext adds one value to a pcontext.Context, like context.WithValue does for context.Context
+type valueContext struct {
+	pcontext.Context
+
+	key   interface{}
+	value interface{}
+}
+
+func (c *valueContext) Value(key interface{}) interface{} {
+	if key == c.key {
+		return c.value
+	}
+
+	return c.Context.Value(key)
+}
+
+func withValue(ctx pcontext.Context, key, value interface{}) pcontext.Context {
+	return &valueContext{
+		Context: ctx,
+		key:     key,
+		value:   value,
+	}
+}
+
+type tableContextKey struct{}
+
+// WithTable returns a context telling the statements run with it operate on tableName,
+// Dao methods set it from the query builder
+func WithTable(ctx pcontext.Context, tableName string) pcontext.Context {
+	return withValue(ctx, tableContextKey{}, tableName)
+}
+
+func TableFromContext(ctx pcontext.Context) string {
+	tableName, _ := ctx.Value(tableContextKey{}).(string)
+
+	return tableName
+}
+
+type argColumnsContextKey struct{}
+
+// WithArgColumns returns a context telling the column each arg of the statements run with it is bound to,
+// it is used to redact args in logs
+func WithArgColumns(ctx pcontext.Context, columns []string) pcontext.Context {
+	return withValue(ctx, argColumnsContextKey{}, columns)
+}
+
+func ArgColumnsFromContext(ctx pcontext.Context) []string {
+	columns, _ := ctx.Value(argColumnsContextKey{}).([]string)
+
+	return columns
+}
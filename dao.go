@@ -2,6 +2,7 @@ package mysql
 
 import (
 	"database/sql"
+	"errors"
 
 	"github.com/goinbox/pcontext"
 )
@@ -9,27 +10,103 @@ import (
 type SqlQueryParams struct {
 	CondItems []*SqlColQueryItem
 
+	// joined with CondItems by AND
+	Condition SqlCondition
+
 	OrderBy string
 	Offset  int64
 	Cnt     int64
 }
 
+// condition returns CondItems and Condition joined by AND
+func (p *SqlQueryParams) condition() SqlCondition {
+	conds := make([]SqlCondition, 0, len(p.CondItems)+1)
+	for _, item := range p.CondItems {
+		conds = append(conds, item)
+	}
+	conds = append(conds, p.Condition)
+
+	return And(conds...)
+}
+
 type SqlExecResult struct {
 	Err          error
 	LastInsertID int64
 	RowsAffected int64
+
+	// set by Dao.Upsert for UpsertResult
+	upsertRows      int
+	clientFoundRows bool
+}
+
+type UpsertResult string
+
+const (
+	UpsertResultInserted  UpsertResult = "inserted"
+	UpsertResultUpdated   UpsertResult = "updated"
+	UpsertResultUnchanged UpsertResult = "unchanged"
+)
+
+// UpsertResult tells how the row of a single row Dao.Upsert was written from RowsAffected, which MySQL reports as
+// 1 for an insert, 2 for an update and 0 if the row is set to its current values.
+// With clientFoundRows set in the DSN an unchanged row reports 1 as well, so 1 returns "" as it can not be told apart.
+// It also returns "" if Err is set or the result is not of a single row upsert.
+func (r *SqlExecResult) UpsertResult() UpsertResult {
+	if r.Err != nil || r.upsertRows != 1 {
+		return ""
+	}
+
+	switch r.RowsAffected {
+	case 0:
+		if !r.clientFoundRows {
+			return UpsertResultUnchanged
+		}
+	case 1:
+		if !r.clientFoundRows {
+			return UpsertResultInserted
+		}
+	case 2:
+		return UpsertResultUpdated
+	}
+
+	return ""
 }
 
 type Dao struct {
 	*Client
 }
 
+// sqbContext tells Client the table and arg columns of the statement built by sqb
+func sqbContext(ctx pcontext.Context, sqb *SqlQueryBuilder) pcontext.Context {
+	return WithArgColumns(WithTable(ctx, sqb.TableName()), sqb.ArgColumns())
+}
+
 func (d *Dao) Insert(ctx pcontext.Context, tableName string, colNames []string, colsValues ...[]interface{}) *SqlExecResult {
 	sqb := new(SqlQueryBuilder)
 	sqb.Insert(tableName, colNames...).
 		Values(colsValues...)
 
-	return ConvertSqlResultToSqlExecResult(d.Exec(ctx, sqb.Query(), sqb.Args()...))
+	return ConvertSqlResultToSqlExecResult(d.Exec(sqbContext(ctx, sqb), sqb.Query(), sqb.Args()...))
+}
+
+// Upsert inserts the rows and updates updateColumns of the rows colliding on a unique key, it fails if updateColumns is empty,
+// see SqlQueryBuilder.OnDuplicateKeyUpdate for SqlInsertedValue and SqlExecResult.UpsertResult for the outcome
+func (d *Dao) Upsert(ctx pcontext.Context, tableName string, colNames []string,
+	updateColumns []*SqlUpdateColumn, colsValues ...[]interface{}) *SqlExecResult {
+	if len(updateColumns) == 0 {
+		return &SqlExecResult{Err: errors.New("upsert has no column to update")}
+	}
+
+	sqb := new(SqlQueryBuilder)
+	sqb.Insert(tableName, colNames...).
+		Values(colsValues...).
+		OnDuplicateKeyUpdate(d.config.UpsertRowAlias, updateColumns)
+
+	result := ConvertSqlResultToSqlExecResult(d.Exec(sqbContext(ctx, sqb), sqb.Query(), sqb.Args()...))
+	result.upsertRows = len(colsValues)
+	result.clientFoundRows = d.config.ClientFoundRows
+
+	return result
 }
 
 func (d *Dao) queryItemForIDs(ids ...int64) *SqlColQueryItem {
@@ -56,7 +133,7 @@ func (d *Dao) DeleteByQueryAnd(ctx pcontext.Context,
 
 	sqb.Delete(tableName).WhereConditionAnd(condItems...)
 
-	return ConvertSqlResultToSqlExecResult(d.Exec(ctx, sqb.Query(), sqb.Args()...))
+	return ConvertSqlResultToSqlExecResult(d.Exec(sqbContext(ctx, sqb), sqb.Query(), sqb.Args()...))
 }
 
 func (d *Dao) DeleteByIDs(ctx pcontext.Context, tableName string, ids ...int64) *SqlExecResult {
@@ -71,7 +148,7 @@ func (d *Dao) UpdateByQueryAnd(ctx pcontext.Context,
 
 	sqb.Update(tableName).Set(updateColumns).WhereConditionAnd(condItems...)
 
-	return ConvertSqlResultToSqlExecResult(d.Exec(ctx, sqb.Query(), sqb.Args()...))
+	return ConvertSqlResultToSqlExecResult(d.Exec(sqbContext(ctx, sqb), sqb.Query(), sqb.Args()...))
 }
 
 func (d *Dao) UpdateByIDs(ctx pcontext.Context,
@@ -86,7 +163,7 @@ func (d *Dao) SelectByID(ctx pcontext.Context, tableName string, what string, id
 	sqb.Select(what, tableName).
 		WhereConditionAnd(&SqlColQueryItem{"id", SqlCondEqual, id, false})
 
-	return d.QueryRow(ctx, sqb.Query(), sqb.Args()...)
+	return d.QueryRow(sqbContext(ctx, sqb), sqb.Query(), sqb.Args()...)
 }
 
 func (d *Dao) SimpleQueryOneAnd(ctx pcontext.Context,
@@ -95,18 +172,18 @@ func (d *Dao) SimpleQueryOneAnd(ctx pcontext.Context,
 	sqb.Select(what, tableName).
 		WhereConditionAnd(condItems...)
 
-	return d.QueryRow(ctx, sqb.Query(), sqb.Args()...)
+	return d.QueryRow(sqbContext(ctx, sqb), sqb.Query(), sqb.Args()...)
 }
 
 func (d *Dao) SimpleQueryAnd(ctx pcontext.Context,
 	tableName string, what string, params *SqlQueryParams) (*sql.Rows, error) {
 	sqb := new(SqlQueryBuilder)
 	sqb.Select(what, tableName).
-		WhereConditionAnd(params.CondItems...).
+		Where(params.condition()).
 		OrderBy(params.OrderBy).
 		Limit(params.Offset, params.Cnt)
 
-	return d.Query(ctx, sqb.Query(), sqb.Args()...)
+	return d.Query(sqbContext(ctx, sqb), sqb.Query(), sqb.Args()...)
 }
 
 func (d *Dao) SimpleTotalAnd(ctx pcontext.Context, tableName string, condItems ...*SqlColQueryItem) (int64, error) {
@@ -115,7 +192,7 @@ func (d *Dao) SimpleTotalAnd(ctx pcontext.Context, tableName string, condItems .
 		WhereConditionAnd(condItems...)
 
 	var total int64
-	err := d.QueryRow(ctx, sqb.Query(), sqb.Args()...).Scan(&total)
+	err := d.QueryRow(sqbContext(ctx, sqb), sqb.Query(), sqb.Args()...).Scan(&total)
 
 	return total, err
 }
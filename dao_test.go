@@ -3,6 +3,7 @@ package mysql
 import (
 	"github.com/goinbox/gomisc"
 
+	"errors"
 	"testing"
 	"time"
 )
@@ -87,3 +88,56 @@ func TestDaoWrite(t *testing.T) {
 	result = dao.DeleteByIDs(ctx, SQL_TEST_TABLE_NAME, id)
 	t.Log(result)
 }
+
+func TestDaoUpsert(t *testing.T) {
+	dao := &Dao{client}
+
+	colNames := []string{"id", "name", "status"}
+	updateColumns := []*SqlUpdateColumn{
+		{
+			Name:  "name",
+			Value: SqlInsertedValue{},
+		},
+		{
+			Name:   "status",
+			Value:  "status + 1",
+			NoBind: true,
+		},
+	}
+	result := dao.Upsert(ctx, SQL_TEST_TABLE_NAME, colNames, updateColumns, []interface{}{1, "upsert", 0})
+	t.Log(result, result.UpsertResult())
+}
+
+func TestSqlExecResultUpsertResult(t *testing.T) {
+	cases := []struct {
+		rowsAffected    int64
+		upsertRows      int
+		clientFoundRows bool
+		expect          UpsertResult
+	}{
+		{0, 1, false, UpsertResultUnchanged},
+		{1, 1, false, UpsertResultInserted},
+		{2, 1, false, UpsertResultUpdated},
+		{1, 1, true, ""},
+		{2, 1, true, UpsertResultUpdated},
+		{3, 3, false, ""},
+		{1, 0, false, ""},
+	}
+	for _, c := range cases {
+		result := &SqlExecResult{RowsAffected: c.rowsAffected, upsertRows: c.upsertRows, clientFoundRows: c.clientFoundRows}
+		if result.UpsertResult() != c.expect {
+			t.Error("upsert result error", c, result.UpsertResult())
+		}
+	}
+
+	result := &SqlExecResult{Err: errors.New("broken"), RowsAffected: 1, upsertRows: 1}
+	if result.UpsertResult() != "" {
+		t.Error("upsert result of error should be empty", result.UpsertResult())
+	}
+
+	dao := &Dao{client}
+	result = dao.Upsert(ctx, SQL_TEST_TABLE_NAME, []string{"name"}, nil, []interface{}{"a"})
+	if result.Err == nil {
+		t.Error("upsert without update columns should fail")
+	}
+}
@@ -0,0 +1,176 @@
+package mysql
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	fingerprintInListRegex = regexp.MustCompile(`\bin\(\?(,\?)*\)`)
+	fingerprintValuesRegex = regexp.MustCompile(`\bvalues\(\?(,\?)*\)(,\(\?(,\?)*\))*`)
+)
+
+// Fingerprint normalizes query into the shape shared by all queries differing only in literals,
+// like pt-query-digest does: comments are dropped, literals and placeholders become ?,
+// IN lists and VALUES rows of any length become (?+), whitespace is collapsed and everything is lower case
+func Fingerprint(query string) string {
+	var sb strings.Builder
+
+	last := "("
+	pendingSpace := false
+	for _, token := range scanSql(query) {
+		switch token.kind {
+		case sqlTokenComment, sqlTokenSpace:
+			pendingSpace = true
+			continue
+		}
+
+		// spaces around parentheses and commas are dropped, so (?, ?) and (?,?) are the same
+		if pendingSpace && last != "(" && last != "," &&
+			token.text != "(" && token.text != ")" && token.text != "," {
+			sb.WriteByte(' ')
+		}
+		pendingSpace = false
+
+		switch token.kind {
+		case sqlTokenString, sqlTokenNumber, sqlTokenPlaceholder:
+			sb.WriteByte('?')
+		case sqlTokenQuotedIdent:
+			sb.WriteString(token.text)
+		default:
+			sb.WriteString(strings.ToLower(token.text))
+		}
+		last = token.text
+	}
+
+	fingerprint := fingerprintInListRegex.ReplaceAllString(sb.String(), "in(?+)")
+	fingerprint = fingerprintValuesRegex.ReplaceAllString(fingerprint, "values(?+)")
+
+	return fingerprint
+}
+
+// Digest returns a stable hex id of the fingerprint of query
+func Digest(query string) string {
+	return digestOfFingerprint(Fingerprint(query))
+}
+
+func digestOfFingerprint(fingerprint string) string {
+	sum := md5.Sum([]byte(fingerprint))
+
+	return hex.EncodeToString(sum[:])
+}
+
+type DigestStat struct {
+	Digest      string
+	Fingerprint string
+
+	Count         int64
+	ErrorCount    int64
+	TotalDuration time.Duration
+	MaxDuration   time.Duration
+}
+
+// DigestAggregator tracks statement stats by digest in process
+type DigestAggregator struct {
+	lock sync.Mutex
+
+	maxDigests int
+	stats      map[string]*DigestStat
+	dropped    int64
+}
+
+// NewDigestAggregator returns an aggregator tracking at most maxDigests digests, <= 0 means unlimited,
+// statements of new digests beyond the limit are only counted by Dropped
+func NewDigestAggregator(maxDigests int) *DigestAggregator {
+	return &DigestAggregator{
+		maxDigests: maxDigests,
+		stats:      map[string]*DigestStat{},
+	}
+}
+
+func (a *DigestAggregator) Observe(query string, duration time.Duration, err error) {
+	fingerprint := Fingerprint(query)
+	digest := digestOfFingerprint(fingerprint)
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	stat, ok := a.stats[digest]
+	if !ok {
+		if a.maxDigests > 0 && len(a.stats) >= a.maxDigests {
+			a.dropped++
+			return
+		}
+
+		stat = &DigestStat{
+			Digest:      digest,
+			Fingerprint: fingerprint,
+		}
+		a.stats[digest] = stat
+	}
+
+	stat.Count++
+	if err != nil {
+		stat.ErrorCount++
+	}
+	stat.TotalDuration += duration
+	if duration > stat.MaxDuration {
+		stat.MaxDuration = duration
+	}
+}
+
+// Snapshot returns a copy of the stats ordered by total duration desc
+func (a *DigestAggregator) Snapshot() []*DigestStat {
+	a.lock.Lock()
+	stats := make([]*DigestStat, 0, len(a.stats))
+	for _, stat := range a.stats {
+		s := *stat
+		stats = append(stats, &s)
+	}
+	a.lock.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].TotalDuration != stats[j].TotalDuration {
+			return stats[i].TotalDuration > stats[j].TotalDuration
+		}
+		return stats[i].Digest < stats[j].Digest
+	})
+
+	return stats
+}
+
+func (a *DigestAggregator) Dropped() int64 {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return a.dropped
+}
+
+func (a *DigestAggregator) Reset() {
+	a.lock.Lock()
+	a.stats = map[string]*DigestStat{}
+	a.dropped = 0
+	a.lock.Unlock()
+}
+
+// DigestStats returns the snapshot of Config.DigestAggregator, nil if it is not set
+func (c *Client) DigestStats() []*DigestStat {
+	if c.config.DigestAggregator == nil {
+		return nil
+	}
+
+	return c.config.DigestAggregator.Snapshot()
+}
+
+func (c *Client) observeDigest(stats *stmtStats) {
+	if c.config.DigestAggregator == nil {
+		return
+	}
+
+	c.config.DigestAggregator.Observe(stats.query, stats.duration, stats.err)
+}
@@ -0,0 +1,71 @@
+package mysql
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFingerprint(t *testing.T) {
+	cases := []struct {
+		query  string
+		expect string
+	}{
+		{
+			"SELECT * FROM demo WHERE name = 'a' AND id IN (1, 2, 3)",
+			"select * from demo where name = ? and id in(?+)",
+		},
+		{
+			"select *  from demo\n where name = ?  /* comment */ and id in (?,?)",
+			"select * from demo where name = ? and id in(?+)",
+		},
+		{
+			"INSERT INTO demo (id, name) VALUES (?, ?), (?, ?)",
+			"insert into demo(id,name) values(?+)",
+		},
+		{
+			"insert into demo (id,name) values (1,'a')",
+			"insert into demo(id,name) values(?+)",
+		},
+		{
+			"SELECT `Name` FROM demo WHERE id NOT IN (5) LIMIT 0, 10 -- page",
+			"select `Name` from demo where id not in(?+) limit ?,?",
+		},
+	}
+
+	for _, c := range cases {
+		if got := Fingerprint(c.query); got != c.expect {
+			t.Errorf("Fingerprint(%q) = %q, expect %q", c.query, got, c.expect)
+		}
+	}
+
+	sqb := new(SqlQueryBuilder)
+	sqb.Select("*", "demo").WhereConditionAnd(&SqlColQueryItem{"id", SqlCondIn, []int{1, 2, 3, 4}, false})
+	if Digest(sqb.Query()) != Digest("select * from demo where id in (7)") {
+		t.Error("digest of builder and raw query should be the same")
+	}
+}
+
+func TestDigestAggregator(t *testing.T) {
+	aggregator := NewDigestAggregator(2)
+	aggregator.Observe("SELECT * FROM demo WHERE id = 1", time.Millisecond, nil)
+	aggregator.Observe("SELECT * FROM demo WHERE id = 2", time.Millisecond*3, errors.New("broken"))
+	aggregator.Observe("UPDATE demo SET name = 'a'", time.Millisecond*2, nil)
+	aggregator.Observe("DELETE FROM demo", time.Millisecond, nil)
+
+	stats := aggregator.Snapshot()
+	if len(stats) != 2 || aggregator.Dropped() != 1 {
+		t.Fatal("snapshot error", len(stats), aggregator.Dropped())
+	}
+
+	stat := stats[0]
+	if stat.Fingerprint != "select * from demo where id = ?" || stat.Count != 2 || stat.ErrorCount != 1 ||
+		stat.TotalDuration != time.Millisecond*4 || stat.MaxDuration != time.Millisecond*3 {
+		t.Error("stat error", stat)
+	}
+
+	aggregator.Reset()
+	if len(aggregator.Snapshot()) != 0 {
+		t.Error("reset error")
+	}
+}
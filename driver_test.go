@@ -0,0 +1,105 @@
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+)
+
+type fakeRows struct {
+	n   int
+	err error
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"id"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.n == 0 {
+		if r.err != nil {
+			return r.err
+		}
+		return io.EOF
+	}
+
+	r.n--
+	dest[0] = int64(r.n)
+
+	return nil
+}
+
+func TestObservedRows(t *testing.T) {
+	errBroken := errors.New("broken")
+	for _, fr := range []*fakeRows{{n: 3}, {n: 2, err: errBroken}} {
+		expectCnt, expectErr := int64(fr.n), fr.err
+
+		calls := 0
+		ctx := withRowsObserver(context.Background(), func(rows int64, err error) {
+			calls++
+			if rows != expectCnt || err != expectErr {
+				t.Error("observer error", rows, err)
+			}
+		})
+
+		rows, _ := wrapRows(ctx, fr, nil)
+		dest := make([]driver.Value, 1)
+		for rows.Next(dest) == nil {
+		}
+		_ = rows.Close()
+		_ = rows.Close()
+
+		if calls != 1 {
+			t.Error("observer should be called once", calls)
+		}
+	}
+
+	rows, _ := wrapRows(context.Background(), &fakeRows{}, nil)
+	if _, ok := rows.(*observedRows); ok {
+		t.Error("rows without observer should not be wrapped")
+	}
+}
+
+// fakeConnector opens connections whose transactions only record how they ended
+type fakeConnector struct {
+	rollbacks int
+}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{c}, nil
+}
+
+func (c *fakeConnector) Driver() driver.Driver {
+	return nil
+}
+
+type fakeConn struct {
+	connector *fakeConnector
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.connector.rollbacks++
+	return nil
+}
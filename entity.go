@@ -159,16 +159,40 @@ type EntityDao struct {
 	Dao
 }
 
-func (d *EntityDao) InsertEntities(ctx pcontext.Context, tableName string, entities ...interface{}) *SqlExecResult {
+// entitiesColsValues returns the columns of the non nil fields of the first entity and the values of every entity
+func entitiesColsValues(entities []interface{}) ([]string, [][]interface{}) {
 	colNames := ReflectColNamesByValue(reflect.ValueOf(entities[0]).Elem(), true)
 	colsValues := make([][]interface{}, len(entities))
 	for i, item := range entities {
 		colsValues[i] = ReflectColValues(reflect.ValueOf(item).Elem(), true)
 	}
 
+	return colNames, colsValues
+}
+
+func (d *EntityDao) InsertEntities(ctx pcontext.Context, tableName string, entities ...interface{}) *SqlExecResult {
+	colNames, colsValues := entitiesColsValues(entities)
+
 	return d.Insert(ctx, tableName, colNames, colsValues...)
 }
 
+// UpsertEntities inserts entities and updates updateColNames of the rows colliding on a unique key to the inserted values,
+// it fails if updateColNames is empty
+func (d *EntityDao) UpsertEntities(ctx pcontext.Context,
+	tableName string, updateColNames []string, entities ...interface{}) *SqlExecResult {
+	colNames, colsValues := entitiesColsValues(entities)
+
+	updateColumns := make([]*SqlUpdateColumn, len(updateColNames))
+	for i, name := range updateColNames {
+		updateColumns[i] = &SqlUpdateColumn{
+			Name:  name,
+			Value: SqlInsertedValue{},
+		}
+	}
+
+	return d.Upsert(ctx, tableName, colNames, updateColumns, colsValues...)
+}
+
 func (d *EntityDao) SelectEntityByID(ctx pcontext.Context, tableName string, id int64, entity interface{}) error {
 	colNames := ReflectColNamesByValue(reflect.ValueOf(entity).Elem(), false)
 	row := d.SelectByID(ctx, tableName, strings.Join(colNames, ","), id)
@@ -31,6 +31,20 @@ func TestInsertEntities(t *testing.T) {
 	t.Log(err)
 }
 
+func TestUpsertEntities(t *testing.T) {
+	id := int64(1)
+	now := time.Now()
+	entity := &demoEntity{
+		ID:       &id,
+		AddTime:  &now,
+		EditTime: &now,
+		Name:     "upsert",
+	}
+
+	result := entityDao().UpsertEntities(ctx, "demo", []string{"edit_time", "name"}, entity)
+	t.Log(result, result.UpsertResult())
+}
+
 func TestSelectEntityByID(t *testing.T) {
 	entity := new(demoEntity)
 	err := entityDao().SelectEntityByID(ctx, "demo", 58, entity)
@@ -1,25 +1,173 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/goinbox/pcontext"
 
 	"github.com/go-sql-driver/mysql"
 )
 
-func DuplicateError(err error) bool {
+// QueryError is returned by Exec, Query and QueryRow when the statement fails,
+// the driver error is kept in Err and reachable by errors.Is and errors.As
+type QueryError struct {
+	// query text and args truncated and redacted the same way as in logs
+	Query string
+	Args  []interface{}
+
+	Pool     string
+	Addr     string
+	InTx     bool
+	Duration time.Duration
+
+	Err error
+}
+
+func (e *QueryError) Error() string {
+	return e.Err.Error() + ", sql: " + e.Query
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+func (c *Client) queryError(ctx pcontext.Context, stats *stmtStats) error {
+	return &QueryError{
+		Query: truncateLogValue(stats.query, c.config.LogMaxSqlLength),
+		Args:  c.redactArgs(ArgColumnsFromContext(ctx), stats.args),
+
+		Pool:     c.key,
+		Addr:     stats.config.Addr,
+		InTx:     c.tx != nil,
+		Duration: stats.duration,
+
+		Err: stats.err,
+	}
+}
+
+func mysqlErrorNumber(err error) (uint16, bool) {
 	var e *mysql.MySQLError
 
 	if errors.As(err, &e) {
-		// mariadb-10.5.9/libmariadb/include/mysqld_error.h:69:#define ER_DUP_ENTRY 1062
-		if e.Number == 1062 {
-			return true
-		}
+		return e.Number, true
+	}
+
+	return 0, false
+}
+
+func DuplicateError(err error) bool {
+	// mariadb-10.5.9/libmariadb/include/mysqld_error.h:69:#define ER_DUP_ENTRY 1062
+	if n, ok := mysqlErrorNumber(err); ok && n == 1062 {
+		return true
+	}
+
+	return false
+}
+
+var duplicateEntryRegex = regexp.MustCompile(`^Duplicate entry '(.*)' for key '([^']*)'$`)
+
+type DuplicateEntry struct {
+	// the duplicated value, parts of a composite key are joined by -
+	Entry string
+	Key   string
+
+	// set only by servers reporting the key as table.key, like MySQL 8.0.19+
+	Table string
+}
+
+// ParseDuplicateError returns the entry and key from the message of a 1062 error,
+// false if err is not one or its message is not recognized
+func ParseDuplicateError(err error) (*DuplicateEntry, bool) {
+	var e *mysql.MySQLError
+	if !errors.As(err, &e) || e.Number != 1062 {
+		return nil, false
+	}
+
+	matches := duplicateEntryRegex.FindStringSubmatch(e.Message)
+	if matches == nil {
+		return nil, false
+	}
+
+	entry := &DuplicateEntry{
+		Entry: matches[1],
+		Key:   matches[2],
+	}
+	if i := strings.LastIndexByte(entry.Key, '.'); i >= 0 {
+		entry.Table = entry.Key[:i]
+		entry.Key = entry.Key[i+1:]
+	}
+
+	return entry, true
+}
+
+func DeadlockError(err error) bool {
+	// mariadb-10.5.9/libmariadb/include/mysqld_error.h:220:#define ER_LOCK_DEADLOCK 1213
+	if n, ok := mysqlErrorNumber(err); ok && n == 1213 {
+		return true
+	}
+
+	return false
+}
+
+func LockWaitTimeoutError(err error) bool {
+	// mariadb-10.5.9/libmariadb/include/mysqld_error.h:212:#define ER_LOCK_WAIT_TIMEOUT 1205
+	if n, ok := mysqlErrorNumber(err); ok && n == 1205 {
+		return true
 	}
 
 	return false
 }
 
+func ForeignKeyError(err error) bool {
+	// mariadb-10.5.9/libmariadb/include/mysqld_error.h:459:#define ER_ROW_IS_REFERENCED_2 1451
+	// mariadb-10.5.9/libmariadb/include/mysqld_error.h:460:#define ER_NO_REFERENCED_ROW_2 1452
+	return mysqlErrorIn(err, 1451, 1452)
+}
+
+func DataTooLongError(err error) bool {
+	// mariadb-10.5.9/libmariadb/include/mysqld_error.h:414:#define ER_DATA_TOO_LONG 1406
+	return mysqlErrorIn(err, 1406)
+}
+
+// ReadOnlyError reports writes refused by a server running with read_only or super_read_only, e.g. a demoted primary,
+// and writes inside a read only transaction
+func ReadOnlyError(err error) bool {
+	// mysql-8.0/include/mysqld_error.h:#define ER_CANT_EXECUTE_IN_READ_ONLY_TRANSACTION 1792
+	return readOnlyServerError(err) || mysqlErrorIn(err, 1792)
+}
+
+func readOnlyServerError(err error) bool {
+	// mariadb-10.5.9/libmariadb/include/mysqld_error.h:298:#define ER_OPTION_PREVENTS_STATEMENT 1290
+	// it is raised by other options too, e.g. --secure-file-priv, the option is named in the message
+	var e *mysql.MySQLError
+	if !errors.As(err, &e) || e.Number != 1290 {
+		return false
+	}
+
+	return strings.Contains(e.Message, "--read-only") || strings.Contains(e.Message, "--super-read-only")
+}
+
+// ConnectionLostError reports a connection dropped by the server or the network,
+// the driver reports it as ErrInvalidConn or driver.ErrBadConn, proxies such as Vitess as the errors 2006 and 2013
+func ConnectionLostError(err error) bool {
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) || mysqlErrorIn(err, 2006, 2013)
+}
+
+func QueryInterruptedError(err error) bool {
+	// mariadb-10.5.9/libmariadb/include/mysqld_error.h:325:#define ER_QUERY_INTERRUPTED 1317
+	return mysqlErrorIn(err, 1317)
+}
+
+func TimeoutError(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
 func NoRowsError(err error) bool {
 	if errors.Is(err, sql.ErrNoRows) {
 		return true
@@ -27,3 +175,84 @@ func NoRowsError(err error) bool {
 
 	return false
 }
+
+func mysqlErrorIn(err error, numbers ...uint16) bool {
+	n, ok := mysqlErrorNumber(err)
+	if !ok {
+		return false
+	}
+
+	for _, number := range numbers {
+		if n == number {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsRetryable reports errors after which running the work again may succeed:
+// deadlocks, lock wait timeouts, lost connections and writes refused by a read only server during failover.
+// Only retry work that is safe to repeat, a connection lost during COMMIT may have committed.
+func IsRetryable(err error) bool {
+	switch ClassifyError(err) {
+	case ErrorClassDeadlock, ErrorClassLockWaitTimeout, ErrorClassConnectionLost:
+		return true
+	case ErrorClassReadOnly:
+		// writes in a read only transaction fail again on every run
+		return readOnlyServerError(err)
+	}
+
+	return false
+}
+
+type ErrorClass string
+
+const (
+	ErrorClassNone             ErrorClass = ""
+	ErrorClassNoRows           ErrorClass = "no_rows"
+	ErrorClassDuplicate        ErrorClass = "duplicate"
+	ErrorClassDeadlock         ErrorClass = "deadlock"
+	ErrorClassLockWaitTimeout  ErrorClass = "lock_wait_timeout"
+	ErrorClassForeignKey       ErrorClass = "foreign_key"
+	ErrorClassDataTooLong      ErrorClass = "data_too_long"
+	ErrorClassReadOnly         ErrorClass = "read_only"
+	ErrorClassConnectionLost   ErrorClass = "connection_lost"
+	ErrorClassQueryInterrupted ErrorClass = "query_interrupted"
+	ErrorClassTimeout          ErrorClass = "timeout"
+	ErrorClassMySQL            ErrorClass = "mysql"
+	ErrorClassOther            ErrorClass = "other"
+)
+
+func ClassifyError(err error) ErrorClass {
+	switch {
+	case err == nil:
+		return ErrorClassNone
+	case NoRowsError(err):
+		return ErrorClassNoRows
+	case DuplicateError(err):
+		return ErrorClassDuplicate
+	case DeadlockError(err):
+		return ErrorClassDeadlock
+	case LockWaitTimeoutError(err):
+		return ErrorClassLockWaitTimeout
+	case ForeignKeyError(err):
+		return ErrorClassForeignKey
+	case DataTooLongError(err):
+		return ErrorClassDataTooLong
+	case ReadOnlyError(err):
+		return ErrorClassReadOnly
+	case TimeoutError(err):
+		return ErrorClassTimeout
+	case QueryInterruptedError(err):
+		return ErrorClassQueryInterrupted
+	case ConnectionLostError(err):
+		return ErrorClassConnectionLost
+	}
+
+	if _, ok := mysqlErrorNumber(err); ok {
+		return ErrorClassMySQL
+	}
+
+	return ErrorClassOther
+}
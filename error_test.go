@@ -0,0 +1,166 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		err       error
+		class     ErrorClass
+		retryable bool
+	}{
+		{nil, ErrorClassNone, false},
+		{sql.ErrNoRows, ErrorClassNoRows, false},
+		{&mysql.MySQLError{Number: 1062}, ErrorClassDuplicate, false},
+		{&mysql.MySQLError{Number: 1213}, ErrorClassDeadlock, true},
+		{fmt.Errorf("update: %w", &mysql.MySQLError{Number: 1205}), ErrorClassLockWaitTimeout, true},
+		{&mysql.MySQLError{Number: 1451}, ErrorClassForeignKey, false},
+		{&mysql.MySQLError{Number: 1452}, ErrorClassForeignKey, false},
+		{&mysql.MySQLError{Number: 1406}, ErrorClassDataTooLong, false},
+		{&mysql.MySQLError{Number: 1290, Message: "The MySQL server is running with the --read-only option so it cannot execute this statement"}, ErrorClassReadOnly, true},
+		{&mysql.MySQLError{Number: 1290, Message: "The MySQL server is running with the --super-read-only option so it cannot execute this statement"}, ErrorClassReadOnly, true},
+		{&mysql.MySQLError{Number: 1290, Message: "The MySQL server is running with the --secure-file-priv option so it cannot execute this statement"}, ErrorClassMySQL, false},
+		{&mysql.MySQLError{Number: 1792}, ErrorClassReadOnly, false},
+		{driver.ErrBadConn, ErrorClassConnectionLost, true},
+		{mysql.ErrInvalidConn, ErrorClassConnectionLost, true},
+		{&mysql.MySQLError{Number: 2006}, ErrorClassConnectionLost, true},
+		{&mysql.MySQLError{Number: 2013}, ErrorClassConnectionLost, true},
+		{&mysql.MySQLError{Number: 1317}, ErrorClassQueryInterrupted, false},
+		{context.DeadlineExceeded, ErrorClassTimeout, false},
+		{&mysql.MySQLError{Number: 1146}, ErrorClassMySQL, false},
+		{errors.New("other"), ErrorClassOther, false},
+	}
+
+	for _, c := range cases {
+		if class := ClassifyError(c.err); class != c.class {
+			t.Errorf("ClassifyError(%v) = %q, expect %q", c.err, class, c.class)
+		}
+		if retryable := IsRetryable(c.err); retryable != c.retryable {
+			t.Errorf("IsRetryable(%v) = %v, expect %v", c.err, retryable, c.retryable)
+		}
+	}
+}
+
+func TestParseDuplicateError(t *testing.T) {
+	cases := []struct {
+		name    string
+		err     error
+		expect  *DuplicateEntry
+		matched bool
+	}{
+		{
+			"mysql 5.7",
+			&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a' for key 'uniq_name'"},
+			&DuplicateEntry{Entry: "a", Key: "uniq_name"},
+			true,
+		},
+		{
+			"mysql 8.0",
+			&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a' for key 'demo.uniq_name'"},
+			&DuplicateEntry{Entry: "a", Key: "uniq_name", Table: "demo"},
+			true,
+		},
+		{
+			"mysql 8.0 primary",
+			&mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1' for key 'demo.PRIMARY'"},
+			&DuplicateEntry{Entry: "1", Key: "PRIMARY", Table: "demo"},
+			true,
+		},
+		{
+			"mariadb composite",
+			&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a-1' for key 'uniq_name_status'"},
+			&DuplicateEntry{Entry: "a-1", Key: "uniq_name_status"},
+			true,
+		},
+		{
+			"quote in entry",
+			fmt.Errorf("insert: %w", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'it's' for key 'PRIMARY'"}),
+			&DuplicateEntry{Entry: "it's", Key: "PRIMARY"},
+			true,
+		},
+		{
+			"empty entry",
+			&mysql.MySQLError{Number: 1062, Message: "Duplicate entry '' for key 'uniq_name'"},
+			&DuplicateEntry{Entry: "", Key: "uniq_name"},
+			true,
+		},
+		{
+			"other number",
+			&mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock; try restarting transaction"},
+			nil,
+			false,
+		},
+		{
+			"unknown message",
+			&mysql.MySQLError{Number: 1062, Message: "Duplicate something"},
+			nil,
+			false,
+		},
+		{
+			"not mysql",
+			errors.New("Duplicate entry 'a' for key 'uniq_name'"),
+			nil,
+			false,
+		},
+	}
+
+	for _, c := range cases {
+		entry, ok := ParseDuplicateError(c.err)
+		if ok != c.matched {
+			t.Errorf("%s: matched %v, expect %v", c.name, ok, c.matched)
+			continue
+		}
+		if ok && *entry != *c.expect {
+			t.Errorf("%s: entry %+v, expect %+v", c.name, entry, c.expect)
+		}
+	}
+}
+
+func TestClientQueryError(t *testing.T) {
+	config := NewDefaultConfig("root", "123", "127.0.0.1", "gobox-demo", 3306)
+	config.LogRedactColumns = []string{"password"}
+	client := &Client{config: config, key: "demo"}
+
+	stats := &stmtStats{
+		config:   config,
+		op:       OpExec,
+		query:    "INSERT INTO user (name, password) VALUES (?, ?)",
+		args:     []interface{}{"a", "secret"},
+		duration: time.Millisecond,
+		err:      &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a' for key 'uniq_name'"},
+	}
+	err := client.queryError(WithArgColumns(ctx, []string{"name", "password"}), stats)
+
+	var qe *QueryError
+	if !errors.As(err, &qe) {
+		t.Fatal("error is not a QueryError", err)
+	}
+	if qe.Query != stats.query || fmt.Sprint(qe.Args) != "[a [REDACTED]]" ||
+		qe.Pool != "demo" || qe.Addr != config.Addr || qe.InTx || qe.Duration != time.Millisecond {
+		t.Error("query error fields error", qe)
+	}
+	if !DuplicateError(err) || ClassifyError(err) != ErrorClassDuplicate {
+		t.Error("query error should unwrap to the driver error")
+	}
+	if _, ok := ParseDuplicateError(err); !ok {
+		t.Error("parse duplicate error failed")
+	}
+}
+
+func TestErrRowQueryError(t *testing.T) {
+	err := &QueryError{Query: "SELECT 1", Err: context.DeadlineExceeded}
+
+	var qe *QueryError
+	if !errors.As(errRow(err).Scan(), &qe) || !TimeoutError(qe) {
+		t.Error("row error should be the QueryError", qe)
+	}
+}
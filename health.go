@@ -0,0 +1,231 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/goinbox/golog"
+)
+
+const (
+	DefaultReplicaCheckInterval = 5 * time.Second
+	DefaultReplicaCheckTimeout  = time.Second
+)
+
+type ReplicaHealthCheckConfig struct {
+	// <= 0 means DefaultReplicaCheckInterval and DefaultReplicaCheckTimeout
+	Interval time.Duration
+	Timeout  time.Duration
+
+	// replicas behind the source more than MaxLag are unhealthy, 0 disables the check
+	MaxLag time.Duration
+}
+
+func NewDefaultReplicaHealthCheckConfig() *ReplicaHealthCheckConfig {
+	return &ReplicaHealthCheckConfig{
+		Interval: DefaultReplicaCheckInterval,
+		Timeout:  DefaultReplicaCheckTimeout,
+	}
+}
+
+// ReplicaHealthChecker pings the replicas of every cluster in a Registry,
+// takes failing or lagging ones out of rotation and puts them back once they recover
+type ReplicaHealthChecker struct {
+	registry *Registry
+	config   *ReplicaHealthCheckConfig
+	logger   golog.Logger
+
+	loop loop
+}
+
+func NewReplicaHealthChecker(registry *Registry,
+	config *ReplicaHealthCheckConfig, logger golog.Logger) *ReplicaHealthChecker {
+	if config == nil {
+		config = NewDefaultReplicaHealthCheckConfig()
+	} else {
+		c := *config
+		config = &c
+	}
+
+	// a zero Interval would panic in Start and a zero Timeout would take every replica out of rotation
+	if config.Interval <= 0 {
+		config.Interval = DefaultReplicaCheckInterval
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultReplicaCheckTimeout
+	}
+
+	return &ReplicaHealthChecker{
+		registry: registry,
+		config:   config,
+		logger:   logger,
+	}
+}
+
+// Start runs CheckOnce every Interval in a goroutine, it does nothing if the checker is already running
+func (h *ReplicaHealthChecker) Start() {
+	h.loop.start(h.config.Interval, h.CheckOnce)
+}
+
+// Stop stops the goroutine of Start and waits for it, it does nothing if the checker is not running
+func (h *ReplicaHealthChecker) Stop() {
+	h.loop.stop()
+}
+
+func (h *ReplicaHealthChecker) CheckOnce() {
+	for key, set := range h.registry.replicaSets() {
+		for _, r := range set.replicas {
+			err := h.check(r)
+			if r.setHealthy(err == nil) {
+				h.logStateChange(key, r, err)
+			}
+		}
+	}
+}
+
+func (h *ReplicaHealthChecker) check(r *replica) error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.Timeout)
+	defer cancel()
+
+	err := r.db.PingContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if h.config.MaxLag <= 0 {
+		return nil
+	}
+
+	lag, err := replicationLag(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	if lag > h.config.MaxLag {
+		return fmt.Errorf("replication lag %s exceeds %s", lag, h.config.MaxLag)
+	}
+
+	return nil
+}
+
+func (h *ReplicaHealthChecker) logStateChange(key string, r *replica, err error) {
+	fields := []*golog.Field{
+		{
+			Key:   "key",
+			Value: key,
+		},
+		{
+			Key:   DefaultLogFieldKeyAddr,
+			Value: r.config.Addr,
+		},
+	}
+
+	if err != nil {
+		h.logger.Warning("replica unhealthy", append(fields, &golog.Field{
+			Key:   "error",
+			Value: err.Error(),
+		})...)
+		return
+	}
+
+	h.logger.Notice("replica recovered", fields...)
+}
+
+// replicationLag reads Seconds_Behind_Source, a server which is not a replica has no lag
+func replicationLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	rows, err := db.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if n, ok := mysqlErrorNumber(err); ok && n == 1064 {
+		// servers before MySQL 8.0.22 only know SHOW SLAVE STATUS
+		rows, err = db.QueryContext(ctx, "SHOW SLAVE STATUS")
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return 0, rows.Err()
+	}
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	values := make([]sql.RawBytes, len(cols))
+	dests := make([]interface{}, len(cols))
+	for i := range values {
+		dests[i] = &values[i]
+	}
+	err = rows.Scan(dests...)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, col := range cols {
+		if col != "Seconds_Behind_Source" && col != "Seconds_Behind_Master" {
+			continue
+		}
+		if values[i] == nil {
+			return 0, errors.New("replication is not running")
+		}
+
+		seconds, err := strconv.ParseInt(string(values[i]), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	return 0, errors.New("replication lag not found in replica status")
+}
+
+// loop runs a function periodically in a goroutine between start and stop
+type loop struct {
+	lock sync.Mutex
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func (l *loop) start(interval time.Duration, f func()) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.done != nil {
+		return
+	}
+	done := make(chan struct{})
+	l.done = done
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				f()
+			}
+		}
+	}()
+}
+
+func (l *loop) stop() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.done == nil {
+		return
+	}
+	close(l.done)
+	l.done = nil
+	l.wg.Wait()
+}
@@ -0,0 +1,60 @@
+package mysql
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReplicaHealthChecker(t *testing.T) {
+	// nothing listens on port 1, so the replicas fail their ping
+	config := &ClusterConfig{
+		Primary: NewDefaultConfig("root", "123", "127.0.0.1", "gobox-demo", 3306),
+		Replicas: []*ReplicaConfig{
+			{Config: NewDefaultConfig("root", "123", "127.0.0.1", "gobox-demo", 1)},
+		},
+	}
+	registry := NewRegistry()
+	if err := registry.RegisterCluster("cluster", config); err != nil {
+		t.Fatal(err)
+	}
+	defer registry.CloseAll()
+
+	checkConfig := NewDefaultReplicaHealthCheckConfig()
+	checkConfig.Interval = time.Millisecond * 10
+
+	checker := NewReplicaHealthChecker(registry, checkConfig, ctx.Logger())
+	checker.Stop()
+
+	replica := registry.replicaSets()["cluster"].replicas[0]
+	if !replica.healthy() {
+		t.Fatal("replica should be healthy before the first check")
+	}
+	checker.CheckOnce()
+	if replica.healthy() {
+		t.Error("replica failing its ping should be unhealthy")
+	}
+
+	replica.setHealthy(true)
+	checker.Start()
+	checker.Start()
+	deadline := time.Now().Add(time.Second)
+	for replica.healthy() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+	}
+	checker.Stop()
+	checker.Stop()
+	if replica.healthy() {
+		t.Error("started checker should mark the replica unhealthy")
+	}
+}
+
+func TestReplicaHealthCheckerDefaults(t *testing.T) {
+	checkConfig := &ReplicaHealthCheckConfig{MaxLag: time.Second}
+	checker := NewReplicaHealthChecker(NewRegistry(), checkConfig, ctx.Logger())
+	if checker.config.Interval != DefaultReplicaCheckInterval || checker.config.Timeout != DefaultReplicaCheckTimeout {
+		t.Error("zero Interval and Timeout should be defaulted", checker.config)
+	}
+	if checker.config.MaxLag != time.Second || checkConfig.Interval != 0 {
+		t.Error("config should be copied without changing the caller's one", checker.config, checkConfig)
+	}
+}
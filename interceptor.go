@@ -0,0 +1,99 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/goinbox/pcontext"
+)
+
+type Operation string
+
+const (
+	OpExec     Operation = "exec"
+	OpQuery    Operation = "query"
+	OpQueryRow Operation = "query_row"
+	OpBegin    Operation = "begin"
+	OpCommit   Operation = "commit"
+	OpRollback Operation = "rollback"
+)
+
+// OpResult holds the result of one operation, only the field of the operation is set
+type OpResult struct {
+	Result sql.Result // OpExec
+	Rows   *sql.Rows  // OpQuery
+	Row    *sql.Row   // OpQueryRow
+	Tx     *Client    // OpBegin
+}
+
+type Handler func(ctx pcontext.Context, query string, args []interface{}) (*OpResult, error)
+
+// Interceptor wraps an operation, it may change ctx, query and args before calling next,
+// observe or replace what next returns, or return without calling next at all.
+// For OpBegin, OpCommit and OpRollback the query is the statement logged for it,
+// rewriting it only takes effect for savepoints.
+type Interceptor func(ctx pcontext.Context, op Operation, query string, args []interface{}, next Handler) (*OpResult, error)
+
+type PrepareQueryFunc func(query string, args ...interface{}) (string, []interface{})
+
+// PrepareQueryInterceptor rewrites the query and args of OpExec, OpQuery and OpQueryRow by f
+func PrepareQueryInterceptor(f PrepareQueryFunc) Interceptor {
+	return func(ctx pcontext.Context, op Operation, query string, args []interface{}, next Handler) (*OpResult, error) {
+		switch op {
+		case OpExec, OpQuery, OpQueryRow:
+			query, args = f(query, args...)
+		}
+
+		return next(ctx, query, args)
+	}
+}
+
+// Use appends interceptors to the chain, the first one is the outermost.
+// Clients returned by Begin inherit the chain, Use should be called before c is shared.
+func (c *Client) Use(interceptors ...Interceptor) *Client {
+	l := len(c.interceptors)
+	c.interceptors = append(c.interceptors[:l:l], interceptors...)
+
+	return c
+}
+
+func (c *Client) invoke(ctx pcontext.Context,
+	op Operation, query string, args []interface{}, handler Handler) (*OpResult, error) {
+	if c.prepareQuery != nil {
+		prepare, next := PrepareQueryInterceptor(c.prepareQuery), handler
+		handler = func(ctx pcontext.Context, query string, args []interface{}) (*OpResult, error) {
+			return prepare(ctx, op, query, args, next)
+		}
+	}
+
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		interceptor, next := c.interceptors[i], handler
+		handler = func(ctx pcontext.Context, query string, args []interface{}) (*OpResult, error) {
+			return interceptor(ctx, op, query, args, next)
+		}
+	}
+
+	return handler(ctx, query, args)
+}
+
+type errConnector struct {
+	err error
+}
+
+func (c *errConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, c.err
+}
+
+func (c *errConnector) Driver() driver.Driver {
+	return nil
+}
+
+// errRow returns a *sql.Row whose Scan returns err, as sql.Row can not be built outside database/sql.
+// It does not use the ctx of the caller, database/sql would return ctx.Err() instead of err once ctx is done.
+func errRow(err error) *sql.Row {
+	db := sql.OpenDB(&errConnector{err})
+	defer db.Close()
+
+	return db.QueryRowContext(context.Background(), "")
+}
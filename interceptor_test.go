@@ -0,0 +1,86 @@
+package mysql
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/goinbox/pcontext"
+)
+
+func TestClientInterceptorChain(t *testing.T) {
+	var trace []string
+	mark := func(name string) Interceptor {
+		return func(ctx pcontext.Context, op Operation, query string, args []interface{}, next Handler) (*OpResult, error) {
+			trace = append(trace, name+" "+string(op))
+			return next(ctx, query+" /*"+name+"*/", args)
+		}
+	}
+
+	client := new(Client).Use(mark("a"), mark("b"))
+	client.SetPrepareQuery(func(query string, args ...interface{}) (string, []interface{}) {
+		return query + " /*replaced*/", args
+	})
+	client.SetPrepareQuery(func(query string, args ...interface{}) (string, []interface{}) {
+		return query, append(args, 1)
+	})
+
+	var gotQuery string
+	var gotArgs []interface{}
+	_, _ = client.invoke(ctx, OpExec, "SELECT 1", nil, func(ctx pcontext.Context, query string, args []interface{}) (*OpResult, error) {
+		gotQuery, gotArgs = query, args
+		return nil, nil
+	})
+
+	if len(trace) != 2 || trace[0] != "a exec" || trace[1] != "b exec" {
+		t.Error("trace error", trace)
+	}
+	if gotQuery != "SELECT 1 /*a*/ /*b*/" || len(gotArgs) != 1 {
+		t.Error("query error", gotQuery, gotArgs)
+	}
+}
+
+func TestClientInterceptorShortCircuit(t *testing.T) {
+	errOpen := errors.New("circuit open")
+	client := &Client{config: NewDefaultConfig("root", "123", "127.0.0.1", "gobox-demo", 3306)}
+	client.Use(func(ctx pcontext.Context, op Operation, query string, args []interface{}, next Handler) (*OpResult, error) {
+		return nil, errOpen
+	})
+
+	_, err := client.Exec(ctx, "SELECT 1")
+	if err != errOpen {
+		t.Error("exec error", err)
+	}
+
+	var n int
+	err = client.QueryRow(ctx, "SELECT 1").Scan(&n)
+	if err != errOpen {
+		t.Error("query row error", err)
+	}
+
+	_, err = client.Begin(ctx)
+	if err != errOpen {
+		t.Error("begin error", err)
+	}
+}
+
+func TestClientInterceptorNoResult(t *testing.T) {
+	client := &Client{config: NewDefaultConfig("root", "123", "127.0.0.1", "gobox-demo", 3306)}
+	client.Use(func(ctx pcontext.Context, op Operation, query string, args []interface{}, next Handler) (*OpResult, error) {
+		return nil, nil
+	})
+
+	result, err := client.Exec(ctx, "SELECT 1")
+	if result != nil || err == nil {
+		t.Error("exec should fail without result", result, err)
+	}
+
+	rows, err := client.Query(ctx, "SELECT 1")
+	if rows != nil || err == nil {
+		t.Error("query should fail without rows", rows, err)
+	}
+
+	dao := &Dao{client}
+	if r := dao.Insert(ctx, "demo", []string{"name"}, []interface{}{"a"}); r.Err == nil {
+		t.Error("insert should fail without result")
+	}
+}
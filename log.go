@@ -0,0 +1,373 @@
+package mysql
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goinbox/golog"
+	"github.com/goinbox/pcontext"
+)
+
+const LogRedactedValue = "[REDACTED]"
+
+// LogRedactFunc returns the value to log instead of value and true, or false to leave value to the other rules,
+// column is "" when the arg is not bound to a known column
+type LogRedactFunc func(column string, value interface{}) (interface{}, bool)
+
+type LogLevel string
+
+const (
+	LogLevelOff     LogLevel = "off"
+	LogLevelDebug   LogLevel = "debug"
+	LogLevelInfo    LogLevel = "info"
+	LogLevelNotice  LogLevel = "notice"
+	LogLevelWarning LogLevel = "warning"
+	LogLevelError   LogLevel = "error"
+)
+
+func (l LogLevel) log(logger golog.Logger, msg string, fields ...*golog.Field) {
+	switch l {
+	case LogLevelOff:
+	case LogLevelDebug:
+		logger.Debug(msg, fields...)
+	case LogLevelNotice:
+		logger.Notice(msg, fields...)
+	case LogLevelWarning:
+		logger.Warning(msg, fields...)
+	case LogLevelError:
+		logger.Error(msg, fields...)
+	default:
+		logger.Info(msg, fields...)
+	}
+}
+
+// logStatement logs one record with the outcome of a finished statement, failures at LogLevelError,
+// others at LogLevelStatement unless LogErrorsOnly is set or it is not sampled by LogSampleRate
+func (c *Client) logStatement(ctx pcontext.Context, stats *stmtStats) {
+	if stats.err == nil && (c.config.LogErrorsOnly || !c.logSampled()) {
+		return
+	}
+
+	fields := c.sqlLogFields(ctx, stats.query, stats.args)
+	fields = append(fields, &golog.Field{
+		Key:   c.config.LogFieldKeyRows,
+		Value: stats.rowsAffected,
+	})
+	if stats.op == OpExec && stats.err == nil {
+		fields = append(fields, &golog.Field{
+			Key:   c.config.LogFieldKeyLastInsertID,
+			Value: stats.lastInsertID,
+		})
+	}
+	fields = append(fields, c.outcomeLogFields(stats.config, stats.duration, c.tx != nil, stats.err)...)
+
+	c.logOutcome(ctx.Logger(), c.config.LogLevelStatement, fields, stats.err)
+}
+
+func (c *Client) logSampled() bool {
+	rate := c.config.LogSampleRate
+	if rate <= 0 || rate >= 1 {
+		return true
+	}
+
+	return rand.Float64() < rate
+}
+
+// logTrans logs BEGIN, COMMIT, ROLLBACK and savepoints once they are done, failures at LogLevelError,
+// others at LogLevelTrans unless LogErrorsOnly is set
+func (c *Client) logTrans(ctx pcontext.Context, query string, start time.Time, err error) {
+	if err == nil && c.config.LogErrorsOnly {
+		return
+	}
+
+	fields := append([]*golog.Field{
+		{
+			Key:   c.config.LogFieldKeySql,
+			Value: query,
+		},
+	}, c.outcomeLogFields(c.config, time.Since(start), true, err)...)
+
+	c.logOutcome(ctx.Logger(), c.config.LogLevelTrans, fields, err)
+}
+
+func (c *Client) logOutcome(logger golog.Logger, level LogLevel, fields []*golog.Field, err error) {
+	if err == nil {
+		level.log(logger, "run sql", fields...)
+		return
+	}
+
+	level = c.config.LogLevelError
+	if level == "" || level == LogLevelOff {
+		level = LogLevelError
+	}
+	level.log(logger, "run sql error", fields...)
+}
+
+// outcomeLogFields returns the fields shared by statements and transaction events run on the DB of config
+func (c *Client) outcomeLogFields(config *Config, duration time.Duration, inTx bool, err error) []*golog.Field {
+	fields := []*golog.Field{
+		{
+			Key:   c.config.LogFieldKeyDuration,
+			Value: duration.String(),
+		},
+		{
+			Key:   c.config.LogFieldKeyInTrans,
+			Value: inTx,
+		},
+		{
+			Key:   c.config.LogFieldKeyAddr,
+			Value: config.Addr,
+		},
+	}
+	if c.key != "" {
+		fields = append(fields, &golog.Field{
+			Key:   c.config.LogFieldKeyPool,
+			Value: c.key,
+		})
+	}
+
+	if err != nil {
+		fields = append(fields, &golog.Field{
+			Key:   c.config.LogFieldKeyError,
+			Value: err.Error(),
+		})
+		if n, ok := mysqlErrorNumber(err); ok {
+			fields = append(fields, &golog.Field{
+				Key:   c.config.LogFieldKeyErrorNumber,
+				Value: n,
+			})
+		}
+	}
+
+	return fields
+}
+
+// sqlLogFields returns the sql field with args redacted and truncated by config,
+// args are interpolated into the sql or given as a separate field if LogArgsAsField is set
+func (c *Client) sqlLogFields(ctx pcontext.Context, query string, args []interface{}) []*golog.Field {
+	args = c.redactArgs(ArgColumnsFromContext(ctx), args)
+
+	if c.config.LogArgsAsField {
+		return []*golog.Field{
+			{
+				Key:   c.config.LogFieldKeySql,
+				Value: truncateLogValue(query, c.config.LogMaxSqlLength),
+			},
+			{
+				Key:   c.config.LogFieldKeyArgs,
+				Value: args,
+			},
+		}
+	}
+
+	return []*golog.Field{
+		{
+			Key:   c.config.LogFieldKeySql,
+			Value: truncateLogValue(InterpolateQuery(query, args, c.config.Loc), c.config.LogMaxSqlLength),
+		},
+	}
+}
+
+func (c *Client) redactArgs(columns []string, args []interface{}) []interface{} {
+	config := c.config
+	if len(config.LogRedactColumns) == 0 && len(config.LogRedactPatterns) == 0 &&
+		config.LogRedactFunc == nil && config.LogMaxArgLength <= 0 {
+		return args
+	}
+
+	redacted := make([]interface{}, len(args))
+	for i, arg := range args {
+		column := ""
+		if i < len(columns) {
+			column = columns[i]
+		}
+
+		v, ok := c.redactArg(column, arg)
+		if !ok {
+			v = truncateLogArg(arg, config.LogMaxArgLength)
+		}
+		redacted[i] = v
+	}
+
+	return redacted
+}
+
+// redactArg returns the value to log and true if arg is redacted
+func (c *Client) redactArg(column string, arg interface{}) (interface{}, bool) {
+	config := c.config
+
+	if config.LogRedactFunc != nil {
+		if v, ok := config.LogRedactFunc(column, arg); ok {
+			return v, true
+		}
+	}
+
+	if column != "" {
+		// t.password is matched by password
+		if i := strings.LastIndexByte(column, '.'); i >= 0 {
+			column = column[i+1:]
+		}
+		for _, name := range config.LogRedactColumns {
+			if strings.EqualFold(column, name) {
+				return LogRedactedValue, true
+			}
+		}
+	}
+
+	if len(config.LogRedactPatterns) > 0 {
+		var s string
+		switch v := arg.(type) {
+		case string:
+			s = v
+		case []byte:
+			s = string(v)
+		default:
+			return arg, false
+		}
+
+		for _, pattern := range config.LogRedactPatterns {
+			if pattern.MatchString(s) {
+				return LogRedactedValue, true
+			}
+		}
+	}
+
+	return arg, false
+}
+
+func truncateLogArg(arg interface{}, maxLength int) interface{} {
+	if maxLength <= 0 {
+		return arg
+	}
+
+	switch v := arg.(type) {
+	case string:
+		return truncateLogValue(v, maxLength)
+	case []byte:
+		if len(v) > maxLength {
+			return hex.EncodeToString(v[:maxLength]) + "...(len " + strconv.Itoa(len(v)) + ")"
+		}
+	}
+
+	return arg
+}
+
+func truncateLogValue(s string, maxLength int) string {
+	if maxLength <= 0 || len(s) <= maxLength {
+		return s
+	}
+
+	return s[:maxLength] + "...(len " + strconv.Itoa(len(s)) + ")"
+}
+
+// CompileLogRedactPatterns compiles patterns for Config.LogRedactPatterns
+func CompileLogRedactPatterns(patterns ...string) ([]*regexp.Regexp, error) {
+	regexps := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		regexps[i] = re
+	}
+
+	return regexps, nil
+}
+
+// InterpolateQuery renders query with args in place of its ? placeholders the way MySQL would read them,
+// placeholders inside quotes and comments are left alone, times are formatted in loc, UTC if nil.
+// It is meant for logs, not for running the result.
+func InterpolateQuery(query string, args []interface{}, loc *time.Location) string {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	var sb strings.Builder
+	i := 0
+	for _, token := range scanSql(query) {
+		if token.kind == sqlTokenPlaceholder && i < len(args) {
+			writeSqlValue(&sb, args[i], loc)
+			i++
+			continue
+		}
+		sb.WriteString(token.text)
+	}
+
+	return sb.String()
+}
+
+func writeSqlValue(sb *strings.Builder, v interface{}, loc *time.Location) {
+	// Valuers, ints, floats, pointers and named types are converted to the types below as database/sql does,
+	// including nil pointers to Valuers with value receivers, which are NULL
+	dv, err := driver.DefaultParameterConverter.ConvertValue(v)
+	if err != nil {
+		writeSqlString(sb, fmt.Sprint(v))
+		return
+	}
+
+	switch v := dv.(type) {
+	case nil:
+		sb.WriteString("NULL")
+	case bool:
+		if v {
+			sb.WriteByte('1')
+		} else {
+			sb.WriteByte('0')
+		}
+	case int64:
+		sb.WriteString(strconv.FormatInt(v, 10))
+	case uint64:
+		sb.WriteString(strconv.FormatUint(v, 10))
+	case float64:
+		sb.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+	case string:
+		writeSqlString(sb, v)
+	case []byte:
+		if v == nil {
+			sb.WriteString("NULL")
+			return
+		}
+		sb.WriteString("X'")
+		sb.WriteString(hex.EncodeToString(v))
+		sb.WriteByte('\'')
+	case time.Time:
+		if v.IsZero() {
+			sb.WriteString("'0000-00-00'")
+			return
+		}
+		sb.WriteByte('\'')
+		sb.WriteString(v.In(loc).Format("2006-01-02 15:04:05.999999"))
+		sb.WriteByte('\'')
+	default:
+		writeSqlString(sb, fmt.Sprint(v))
+	}
+}
+
+// writeSqlString quotes and escapes s like mysql_real_escape_string
+func writeSqlString(sb *strings.Builder, s string) {
+	sb.WriteByte('\'')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case 0:
+			sb.WriteString(`\0`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\x1a':
+			sb.WriteString(`\Z`)
+		case '\'', '"', '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteByte('\'')
+}
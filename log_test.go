@@ -0,0 +1,141 @@
+package mysql
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestClientSqlLogFields(t *testing.T) {
+	config := NewDefaultConfig("root", "123", "127.0.0.1", "gobox-demo", 3306)
+	config.LogRedactColumns = []string{"password"}
+	config.LogRedactPatterns = []*regexp.Regexp{regexp.MustCompile(`^tk_`)}
+	config.LogRedactFunc = func(column string, value interface{}) (interface{}, bool) {
+		if column == "phone" {
+			return "***" + value.(string)[7:], true
+		}
+		return nil, false
+	}
+	config.LogMaxArgLength = 8
+	client := &Client{config: config}
+
+	lctx := WithArgColumns(ctx, []string{"u.password", "phone", "name", "token", "bio"})
+	query := "UPDATE user u SET u.password = ?, phone = ?, name = ?, token = ?, bio = ?"
+	args := []interface{}{"secret", "13800001234", "a", "tk_abc", strings.Repeat("x", 20)}
+
+	fields := client.sqlLogFields(lctx, query, args)
+	expect := "UPDATE user u SET u.password = '[REDACTED]', phone = '***1234', name = 'a', token = '[REDACTED]', bio = 'xxxxxxxx...(len 20)'"
+	if len(fields) != 1 || fields[0].Value != expect {
+		t.Error("sql field error", fields[0].Value)
+	}
+
+	config.LogArgsAsField = true
+	config.LogMaxSqlLength = 10
+	fields = client.sqlLogFields(lctx, query, args)
+	if len(fields) != 2 || fields[0].Value != "UPDATE use...(len 73)" {
+		t.Error("sql field error", fields[0].Value)
+	}
+	if fields[1].Key != DefaultLogFieldKeyArgs || fields[1].Value.([]interface{})[0] != LogRedactedValue {
+		t.Error("args field error", fields[1])
+	}
+	if args[0] != "secret" {
+		t.Error("args should not be changed")
+	}
+}
+
+func TestInterpolateQuery(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	tm := time.Date(2021, 3, 4, 5, 6, 7, 123000000, time.UTC)
+	status := 2
+
+	cases := []struct {
+		query  string
+		args   []interface{}
+		expect string
+	}{
+		{
+			"SELECT * FROM demo WHERE name = ? AND status = ? AND id IN (?, ?)",
+			[]interface{}{"a", &status, int64(1), uint8(2)},
+			"SELECT * FROM demo WHERE name = 'a' AND status = 2 AND id IN (1, 2)",
+		},
+		{
+			"SELECT '?', `a?` /* ? */, ? -- ?\n, ?",
+			[]interface{}{"it's \"ok\" \\ \n", nil},
+			"SELECT '?', `a?` /* ? */, 'it\\'s \\\"ok\\\" \\\\ \\n' -- ?\n, NULL",
+		},
+		{
+			"INSERT INTO demo VALUES (?, ?, ?, ?, ?, ?)",
+			[]interface{}{[]byte{0x01, 0xab}, tm, sql.NullString{}, sql.NullInt64{Int64: 3, Valid: true}, true, 1.5},
+			"INSERT INTO demo VALUES (X'01ab', '2021-03-04 13:06:07.123', NULL, 3, 1, 1.5)",
+		},
+		{
+			"SELECT ?, ?",
+			[]interface{}{1},
+			"SELECT 1, ?",
+		},
+		{
+			"UPDATE demo SET name = ?, status = ?",
+			[]interface{}{(*sql.NullString)(nil), (*int)(nil)},
+			"UPDATE demo SET name = NULL, status = NULL",
+		},
+	}
+
+	for _, c := range cases {
+		if got := InterpolateQuery(c.query, c.args, loc); got != c.expect {
+			t.Errorf("InterpolateQuery(%q) = %q, expect %q", c.query, got, c.expect)
+		}
+	}
+}
+
+func TestClientLogSampled(t *testing.T) {
+	config := NewDefaultConfig("root", "123", "127.0.0.1", "gobox-demo", 3306)
+	client := &Client{config: config}
+
+	for _, rate := range []float64{0, 1, 2} {
+		config.LogSampleRate = rate
+		if !client.logSampled() {
+			t.Error("rate", rate, "should log every statement")
+		}
+	}
+
+	config.LogSampleRate = 0.2
+	cnt := 0
+	for i := 0; i < 10000; i++ {
+		if client.logSampled() {
+			cnt++
+		}
+	}
+	if cnt < 1500 || cnt > 2500 {
+		t.Error("sampled count error", cnt)
+	}
+}
+
+func TestClientOutcomeLogFields(t *testing.T) {
+	config := NewDefaultConfig("root", "123", "127.0.0.1", "gobox-demo", 3306)
+	client := &Client{config: config, key: "demo"}
+
+	err := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1' for key 'PRIMARY'"}
+	fields := client.outcomeLogFields(config, time.Millisecond, true, err)
+
+	values := map[string]interface{}{}
+	for _, field := range fields {
+		values[field.Key] = field.Value
+	}
+	expects := map[string]interface{}{
+		DefaultLogFieldKeyDuration:    "1ms",
+		DefaultLogFieldKeyInTrans:     true,
+		DefaultLogFieldKeyAddr:        "127.0.0.1:3306",
+		DefaultLogFieldKeyPool:        "demo",
+		DefaultLogFieldKeyError:       err.Error(),
+		DefaultLogFieldKeyErrorNumber: uint16(1062),
+	}
+	for key, value := range expects {
+		if values[key] != value {
+			t.Error("field error", key, values[key])
+		}
+	}
+}
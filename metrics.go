@@ -0,0 +1,160 @@
+package mysql
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+)
+
+const DefaultPoolStatsExportInterval = 15 * time.Second
+
+type StatementMetric struct {
+	Op         Operation
+	Table      string
+	Duration   time.Duration
+	ErrorClass ErrorClass
+}
+
+// MetricsCollector receives the metrics of Client statements and DB pools.
+// A Prometheus adapter maps ObserveStatement to a counter and a histogram labeled by op, table and error class,
+// and ObservePoolStats to gauges labeled by key.
+type MetricsCollector interface {
+	// ObserveStatement is called once for each finished Exec, Query or QueryRow
+	ObserveStatement(metric *StatementMetric)
+
+	// ObservePoolStats is called by PoolStatsExporter for every pool in its Registry
+	ObservePoolStats(key string, stats sql.DBStats)
+}
+
+func (c *Client) observeMetrics(stats *stmtStats) {
+	collector := c.config.MetricsCollector
+	if collector == nil {
+		return
+	}
+
+	collector.ObserveStatement(&StatementMetric{
+		Op:         stats.op,
+		Table:      stats.table,
+		Duration:   stats.duration,
+		ErrorClass: ClassifyError(stats.err),
+	})
+}
+
+// PoolStatsExporter reports sql.DBStats of every pool in a Registry to a MetricsCollector periodically
+type PoolStatsExporter struct {
+	registry  *Registry
+	collector MetricsCollector
+	interval  time.Duration
+
+	loop loop
+}
+
+// NewPoolStatsExporter exports every interval, <= 0 means DefaultPoolStatsExportInterval
+func NewPoolStatsExporter(registry *Registry, collector MetricsCollector, interval time.Duration) *PoolStatsExporter {
+	if interval <= 0 {
+		interval = DefaultPoolStatsExportInterval
+	}
+
+	return &PoolStatsExporter{
+		registry:  registry,
+		collector: collector,
+		interval:  interval,
+	}
+}
+
+// Start runs ExportOnce every interval in a goroutine, it does nothing if the exporter is already running
+func (e *PoolStatsExporter) Start() {
+	e.loop.start(e.interval, e.ExportOnce)
+}
+
+// Stop stops the goroutine of Start and waits for it, it does nothing if the exporter is not running
+func (e *PoolStatsExporter) Stop() {
+	e.loop.stop()
+}
+
+func (e *PoolStatsExporter) ExportOnce() {
+	for key, stats := range e.registry.Stats() {
+		e.collector.ObservePoolStats(key, stats)
+	}
+}
+
+type StatementMetricKey struct {
+	Op         Operation
+	Table      string
+	ErrorClass ErrorClass
+}
+
+type StatementMetricValue struct {
+	Count         int64
+	TotalDuration time.Duration
+	MaxDuration   time.Duration
+}
+
+// MemoryMetrics is a MetricsCollector keeping everything in memory, it is meant for tests and debugging
+type MemoryMetrics struct {
+	lock sync.Mutex
+
+	statements map[StatementMetricKey]*StatementMetricValue
+	pools      map[string]sql.DBStats
+}
+
+func NewMemoryMetrics() *MemoryMetrics {
+	return &MemoryMetrics{
+		statements: map[StatementMetricKey]*StatementMetricValue{},
+		pools:      map[string]sql.DBStats{},
+	}
+}
+
+func (m *MemoryMetrics) ObserveStatement(metric *StatementMetric) {
+	key := StatementMetricKey{
+		Op:         metric.Op,
+		Table:      metric.Table,
+		ErrorClass: metric.ErrorClass,
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	value, ok := m.statements[key]
+	if !ok {
+		value = new(StatementMetricValue)
+		m.statements[key] = value
+	}
+	value.Count++
+	value.TotalDuration += metric.Duration
+	if metric.Duration > value.MaxDuration {
+		value.MaxDuration = metric.Duration
+	}
+}
+
+func (m *MemoryMetrics) ObservePoolStats(key string, stats sql.DBStats) {
+	m.lock.Lock()
+	m.pools[key] = stats
+	m.lock.Unlock()
+}
+
+// Statements returns a copy of the statement metrics
+func (m *MemoryMetrics) Statements() map[StatementMetricKey]StatementMetricValue {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	statements := make(map[StatementMetricKey]StatementMetricValue, len(m.statements))
+	for key, value := range m.statements {
+		statements[key] = *value
+	}
+
+	return statements
+}
+
+// PoolStats returns a copy of the latest pool stats by key
+func (m *MemoryMetrics) PoolStats() map[string]sql.DBStats {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	pools := make(map[string]sql.DBStats, len(m.pools))
+	for key, stats := range m.pools {
+		pools[key] = stats
+	}
+
+	return pools
+}
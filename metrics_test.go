@@ -0,0 +1,58 @@
+package mysql
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryMetrics(t *testing.T) {
+	metrics := NewMemoryMetrics()
+	config := NewDefaultConfig("root", "123", "127.0.0.1", "gobox-demo", 3306)
+	config.MetricsCollector = metrics
+	client := &Client{config: config}
+
+	tctx := WithTable(ctx, "demo")
+	for _, d := range []time.Duration{time.Millisecond, time.Millisecond * 3} {
+		stats := client.newStmtStats(tctx, OpExec, "UPDATE demo SET status = 1", nil, config)
+		stats.start = stats.start.Add(-d)
+		client.statementDone(tctx, stats.done(nil))
+	}
+	client.statementDone(tctx, client.newStmtStats(tctx, OpQuery, "SELECT * FROM demo", nil, config).done(errors.New("broken")))
+
+	statements := metrics.Statements()
+	value := statements[StatementMetricKey{OpExec, "demo", ErrorClassNone}]
+	if value.Count != 2 || value.MaxDuration < time.Millisecond*3 || value.TotalDuration < time.Millisecond*4 {
+		t.Error("exec metric error", value)
+	}
+	value = statements[StatementMetricKey{OpQuery, "demo", ErrorClassOther}]
+	if value.Count != 1 {
+		t.Error("query metric error", value)
+	}
+}
+
+func TestPoolStatsExporter(t *testing.T) {
+	registry := NewRegistry()
+	_ = registry.RegisterCluster("cluster", clusterConfig(ReplicaBalanceRoundRobin))
+	defer registry.CloseAll()
+
+	metrics := NewMemoryMetrics()
+	exporter := NewPoolStatsExporter(registry, metrics, time.Millisecond*10)
+	exporter.Stop()
+	exporter.Start()
+	exporter.Start()
+	time.Sleep(time.Millisecond * 50)
+	exporter.Stop()
+	exporter.Stop()
+
+	pools := metrics.PoolStats()
+	for _, key := range []string{"cluster", "cluster/127.0.0.2:3306", "cluster/127.0.0.3:3306"} {
+		if _, ok := pools[key]; !ok {
+			t.Error("pool stats not exported", key)
+		}
+	}
+
+	if NewPoolStatsExporter(registry, metrics, 0).interval != DefaultPoolStatsExportInterval {
+		t.Error("zero interval should be defaulted")
+	}
+}
@@ -0,0 +1,325 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultRegistryDrainTimeout = 30 * time.Second
+
+	registryDrainInterval = 100 * time.Millisecond
+)
+
+type dbItem struct {
+	config *Config
+	db     *sql.DB
+
+	replicas *replicaSet
+}
+
+func newDBItem(config *Config) (*dbItem, error) {
+	db, err := newDB(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dbItem{
+		config: config,
+		db:     db,
+	}, nil
+}
+
+func newClusterItem(config *ClusterConfig) (*dbItem, error) {
+	if config.Primary == nil {
+		return nil, errors.New("cluster has no primary")
+	}
+
+	item, err := newDBItem(config.Primary)
+	if err != nil {
+		return nil, err
+	}
+
+	item.replicas, err = newReplicaSet(config)
+	if err != nil {
+		_ = item.db.Close()
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// dbPool is what a Client runs on, Registry.Replace swaps its item so that existing clients follow the new pool
+type dbPool struct {
+	item atomic.Value // *dbItem
+}
+
+func newDBPool(item *dbItem) *dbPool {
+	pool := new(dbPool)
+	pool.item.Store(item)
+
+	return pool
+}
+
+func (p *dbPool) load() *dbItem {
+	return p.item.Load().(*dbItem)
+}
+
+// Registry is a concurrency-safe set of named DB pools
+type Registry struct {
+	// max wait for the connections of a replaced or unregistered pool to be released before it is closed
+	DrainTimeout time.Duration
+
+	lock  sync.RWMutex
+	pools map[string]*dbPool
+}
+
+var DefaultRegistry = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{
+		DrainTimeout: DefaultRegistryDrainTimeout,
+
+		pools: map[string]*dbPool{},
+	}
+}
+
+// Register opens a pool for config under key, it fails if key is already registered, use Replace for that
+func (r *Registry) Register(key string, config *Config) error {
+	item, err := newDBItem(config)
+	if err != nil {
+		return err
+	}
+
+	return r.add(key, item)
+}
+
+// RegisterCluster opens pools for the primary and replicas of config under key
+func (r *Registry) RegisterCluster(key string, config *ClusterConfig) error {
+	item, err := newClusterItem(config)
+	if err != nil {
+		return err
+	}
+
+	return r.add(key, item)
+}
+
+func (r *Registry) add(key string, item *dbItem) error {
+	r.lock.Lock()
+	_, ok := r.pools[key]
+	if !ok {
+		r.pools[key] = newDBPool(item)
+	}
+	r.lock.Unlock()
+
+	if ok {
+		_ = item.close()
+		return errors.New("DB " + key + " already exist")
+	}
+
+	return nil
+}
+
+// Replace registers a new pool for config under key, clients of key run their next statements on it at once,
+// keeping the log, metrics and tracing settings of the config they were made with.
+// It returns once the previous pool is drained and closed, see DrainTimeout.
+func (r *Registry) Replace(key string, config *Config) error {
+	item, err := newDBItem(config)
+	if err != nil {
+		return err
+	}
+
+	return r.replace(key, item)
+}
+
+func (r *Registry) ReplaceCluster(key string, config *ClusterConfig) error {
+	item, err := newClusterItem(config)
+	if err != nil {
+		return err
+	}
+
+	return r.replace(key, item)
+}
+
+func (r *Registry) replace(key string, item *dbItem) error {
+	var old *dbItem
+	r.lock.Lock()
+	pool, ok := r.pools[key]
+	if ok {
+		old = pool.load()
+		pool.item.Store(item)
+	} else {
+		r.pools[key] = newDBPool(item)
+	}
+	r.lock.Unlock()
+
+	if old != nil {
+		return old.drainClose(r.DrainTimeout)
+	}
+
+	return nil
+}
+
+// Unregister removes the pool of key, drains and closes it, clients of key fail from then on
+func (r *Registry) Unregister(key string) error {
+	r.lock.Lock()
+	pool, ok := r.pools[key]
+	delete(r.pools, key)
+	r.lock.Unlock()
+
+	if !ok {
+		return errors.New("DB " + key + " not exist")
+	}
+
+	return pool.load().drainClose(r.DrainTimeout)
+}
+
+func (r *Registry) Keys() []string {
+	r.lock.RLock()
+	keys := make([]string, 0, len(r.pools))
+	for key := range r.pools {
+		keys = append(keys, key)
+	}
+	r.lock.RUnlock()
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// CloseAll unregisters, drains and closes every pool, it is used for graceful shutdown
+func (r *Registry) CloseAll() error {
+	r.lock.Lock()
+	pools := r.pools
+	r.pools = map[string]*dbPool{}
+	r.lock.Unlock()
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	var errs []string
+	for key, pool := range pools {
+		wg.Add(1)
+		go func(key string, item *dbItem) {
+			defer wg.Done()
+
+			err := item.drainClose(r.DrainTimeout)
+			if err != nil {
+				lock.Lock()
+				errs = append(errs, key+": "+err.Error())
+				lock.Unlock()
+			}
+		}(key, pool.load())
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("close DB error: %v", errs)
+	}
+
+	return nil
+}
+
+func (r *Registry) replicaSets() map[string]*replicaSet {
+	sets := map[string]*replicaSet{}
+
+	r.lock.RLock()
+	for key, pool := range r.pools {
+		if item := pool.load(); item.replicas != nil {
+			sets[key] = item.replicas
+		}
+	}
+	r.lock.RUnlock()
+
+	return sets
+}
+
+// Stats returns the stats of every pool, replicas of a cluster are reported as key/addr
+func (r *Registry) Stats() map[string]sql.DBStats {
+	stats := map[string]sql.DBStats{}
+
+	r.lock.RLock()
+	for key, pool := range r.pools {
+		item := pool.load()
+		stats[key] = item.db.Stats()
+		if item.replicas != nil {
+			for _, replica := range item.replicas.replicas {
+				stats[key+"/"+replica.config.Addr] = replica.db.Stats()
+			}
+		}
+	}
+	r.lock.RUnlock()
+
+	return stats
+}
+
+func (r *Registry) NewClient(key string) (*Client, error) {
+	r.lock.RLock()
+	pool, ok := r.pools[key]
+	r.lock.RUnlock()
+
+	if !ok {
+		return nil, errors.New("DB " + key + " not exist")
+	}
+
+	client := newClient(pool, pool.load().config)
+	client.key = key
+
+	return client, nil
+}
+
+func (i *dbItem) close() error {
+	err := i.db.Close()
+	if i.replicas != nil {
+		if rerr := i.replicas.close(); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
+
+	return err
+}
+
+// drainClose closes the DBs of i once none of their connections is in use or timeout passes,
+// as sql.DB.Close does not wait, it fails the calls still waiting for a connection
+func (i *dbItem) drainClose(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for i.inUse() && time.Now().Before(deadline) {
+		time.Sleep(registryDrainInterval)
+	}
+
+	return i.close()
+}
+
+func (i *dbItem) inUse() bool {
+	if i.db.Stats().InUse > 0 {
+		return true
+	}
+	if i.replicas != nil {
+		for _, r := range i.replicas.replicas {
+			if r.db.Stats().InUse > 0 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// RegisterDB registers config under key in DefaultRegistry, a pool already registered under key
+// is replaced as by DefaultRegistry.Replace
+func RegisterDB(key string, config *Config) error {
+	return DefaultRegistry.Replace(key, config)
+}
+
+func RegisterClusterDB(key string, config *ClusterConfig) error {
+	return DefaultRegistry.ReplaceCluster(key, config)
+}
+
+func NewClientFromPool(key string) (*Client, error) {
+	return DefaultRegistry.NewClient(key)
+}
@@ -0,0 +1,63 @@
+package mysql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	config := NewDefaultConfig("root", "123", "127.0.0.1", "gobox-demo", 3306)
+
+	key := "test"
+	t.Log(registry.Register(key, config))
+	if err := registry.Register(key, config); err == nil {
+		t.Error("register exist key should fail")
+	}
+
+	client, _ := registry.NewClient(key)
+	old := client.pool.load().db
+	t.Log(registry.Replace(key, config))
+	if client.pool.load().db == old {
+		t.Error("client made before Replace should run on the new pool")
+	}
+	if err := old.Ping(); err == nil || !strings.Contains(err.Error(), "database is closed") {
+		t.Error("replaced pool should be closed", err)
+	}
+	t.Log(registry.Replace("test2", config))
+
+	keys := registry.Keys()
+	if len(keys) != 2 || keys[0] != "test" || keys[1] != "test2" {
+		t.Error("keys error", keys)
+	}
+
+	_, err := registry.NewClient(key)
+	if err != nil {
+		t.Error("new client error", err)
+	}
+
+	t.Log(registry.Unregister(key))
+	if _, err = registry.NewClient(key); err == nil {
+		t.Error("new client from unregistered key should fail")
+	}
+	if err = registry.Unregister(key); err == nil {
+		t.Error("unregister not exist key should fail")
+	}
+
+	t.Log(registry.CloseAll())
+	if len(registry.Keys()) != 0 {
+		t.Error("keys should be empty after CloseAll", registry.Keys())
+	}
+}
+
+func TestRegisterDB(t *testing.T) {
+	config := NewDefaultConfig("root", "123", "127.0.0.1", "gobox-demo", 3306)
+
+	key := "test_register_db"
+	defer DefaultRegistry.Unregister(key)
+	for i := 0; i < 2; i++ {
+		if err := RegisterDB(key, config); err != nil {
+			t.Error("register db should replace the pool of an exist key", err)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package mysql
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/goinbox/golog"
+	"github.com/goinbox/pcontext"
+)
+
+const (
+	DefaultTxRetryMaxAttempts = 3
+	DefaultTxRetryBackoff     = 50 * time.Millisecond
+	DefaultTxRetryMaxBackoff  = time.Second
+	DefaultTxRetryJitter      = 0.2
+)
+
+type TxRetryPolicy struct {
+	// total runs of the transaction, including the first one
+	MaxAttempts int
+
+	// wait before the first retry, doubled for each following retry up to MaxBackoff, 0 MaxBackoff means no cap
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// fraction of the wait randomised in both directions, 0.2 means wait * [0.8, 1.2)
+	Jitter float64
+
+	Retryable func(err error) bool
+}
+
+func NewDefaultTxRetryPolicy() *TxRetryPolicy {
+	return &TxRetryPolicy{
+		MaxAttempts: DefaultTxRetryMaxAttempts,
+		Backoff:     DefaultTxRetryBackoff,
+		MaxBackoff:  DefaultTxRetryMaxBackoff,
+		Jitter:      DefaultTxRetryJitter,
+		Retryable:   RetryableTxError,
+	}
+}
+
+// RetryableTxError reports deadlocks and lock wait timeouts, which roll back the transaction on the server,
+// set TxRetryPolicy.Retryable to IsRetryable to retry on lost connections and failovers as well
+func RetryableTxError(err error) bool {
+	return DeadlockError(err) || LockWaitTimeoutError(err)
+}
+
+func (p *TxRetryPolicy) wait(retry int) time.Duration {
+	wait := p.Backoff
+	for i := 1; i < retry && (p.MaxBackoff <= 0 || wait < p.MaxBackoff); i++ {
+		wait *= 2
+	}
+	if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+
+	if p.Jitter > 0 {
+		wait = time.Duration(float64(wait) * (1 + p.Jitter*(rand.Float64()*2-1)))
+	}
+
+	return wait
+}
+
+// WithTransactionRetry runs f by WithTransactionOptions and runs it again in a new transaction
+// when it fails with an error accepted by policy.Retryable.
+// Inside a transaction f runs only once, as the failure has already rolled back the outer transaction.
+func (c *Client) WithTransactionRetry(ctx pcontext.Context,
+	policy *TxRetryPolicy, opts *TxOptions, f TransactionFunc) error {
+	if policy == nil {
+		policy = NewDefaultTxRetryPolicy()
+	}
+	if c.tx != nil {
+		return c.WithTransactionOptions(ctx, opts, f)
+	}
+
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = RetryableTxError
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = c.WithTransactionOptions(ctx, opts, f)
+		if err == nil {
+			if attempt > 1 {
+				ctx.Logger().Info("trans succeeded after retry", &golog.Field{
+					Key:   "attempt",
+					Value: attempt,
+				})
+			}
+			return nil
+		}
+
+		if attempt >= policy.MaxAttempts || !retryable(err) {
+			break
+		}
+
+		wait := policy.wait(attempt)
+		ctx.Logger().Warning("retry trans", &golog.Field{
+			Key:   "attempt",
+			Value: attempt,
+		}, &golog.Field{
+			Key:   "max_attempts",
+			Value: policy.MaxAttempts,
+		}, &golog.Field{
+			Key:   "wait",
+			Value: wait.String(),
+		}, &golog.Field{
+			Key:   "error",
+			Value: err.Error(),
+		})
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+
+	return err
+}
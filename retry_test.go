@@ -0,0 +1,72 @@
+package mysql
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/goinbox/pcontext"
+)
+
+func TestClientWithTransactionRetry(t *testing.T) {
+	policy := NewDefaultTxRetryPolicy()
+	policy.Backoff = time.Millisecond * 10
+
+	attempts := 0
+	err := client.WithTransactionRetry(ctx, policy, nil, func(tx *Client) error {
+		attempts++
+		_, err := tx.Exec(ctx, "update demo set status = status + 1 where id = 1")
+		return err
+	})
+	t.Log(err, attempts)
+}
+
+func TestTxRetryPolicyWait(t *testing.T) {
+	policy := &TxRetryPolicy{
+		Backoff:    time.Millisecond * 10,
+		MaxBackoff: time.Millisecond * 50,
+	}
+
+	for retry, expect := range []time.Duration{10, 20, 40, 50, 50} {
+		wait := policy.wait(retry + 1)
+		if wait != expect*time.Millisecond {
+			t.Error(retry+1, wait)
+		}
+	}
+
+	policy.MaxBackoff = 0
+	if wait := policy.wait(5); wait != 160*time.Millisecond {
+		t.Error("wait without MaxBackoff should not be capped", wait)
+	}
+}
+
+func TestClientWithTransactionRetryAttempts(t *testing.T) {
+	policy := NewDefaultTxRetryPolicy()
+	policy.Backoff = time.Millisecond
+	policy.Jitter = 0
+
+	var beginErr error
+	begins := 0
+	client := &Client{config: NewDefaultConfig("root", "123", "127.0.0.1", "gobox-demo", 3306)}
+	client.Use(func(ctx pcontext.Context, op Operation, query string, args []interface{}, next Handler) (*OpResult, error) {
+		begins++
+		return nil, beginErr
+	})
+	f := func(tx *Client) error {
+		return nil
+	}
+
+	beginErr = &mysql.MySQLError{Number: 1213}
+	err := client.WithTransactionRetry(ctx, policy, nil, f)
+	if !DeadlockError(err) || begins != policy.MaxAttempts {
+		t.Error("retryable error should be retried up to MaxAttempts", err, begins)
+	}
+
+	beginErr = errors.New("not retryable")
+	begins = 0
+	err = client.WithTransactionRetry(ctx, policy, nil, f)
+	if err != beginErr || begins != 1 {
+		t.Error("not retryable error should stop the retry", err, begins)
+	}
+}
@@ -0,0 +1,130 @@
+package mysql
+
+// SqlCondition is a condition of WHERE, HAVING or JOIN ON,
+// *SqlColQueryItem and Exists are the leaves, And, Or and Not combine conditions into trees
+type SqlCondition interface {
+	// buildSqlCondition appends the condition to the query of s, nested conditions with several parts are parenthesized
+	buildSqlCondition(s *SqlQueryBuilder, nested bool)
+	emptySqlCondition() bool
+}
+
+func (item *SqlColQueryItem) buildSqlCondition(s *SqlQueryBuilder, nested bool) {
+	s.buildCondition(item)
+}
+
+func (item *SqlColQueryItem) emptySqlCondition() bool {
+	return item == nil
+}
+
+type sqlConditionGroup struct {
+	andOr string
+	conds []SqlCondition
+}
+
+// And joins conds by AND, nil and empty conds are skipped
+func And(conds ...SqlCondition) SqlCondition {
+	return &sqlConditionGroup{
+		andOr: "AND",
+		conds: conds,
+	}
+}
+
+// Or joins conds by OR, nil and empty conds are skipped
+func Or(conds ...SqlCondition) SqlCondition {
+	return &sqlConditionGroup{
+		andOr: "OR",
+		conds: conds,
+	}
+}
+
+func (g *sqlConditionGroup) buildSqlCondition(s *SqlQueryBuilder, nested bool) {
+	conds := make([]SqlCondition, 0, len(g.conds))
+	for _, cond := range g.conds {
+		if !sqlConditionEmpty(cond) {
+			conds = append(conds, cond)
+		}
+	}
+
+	if len(conds) == 1 {
+		conds[0].buildSqlCondition(s, nested)
+		return
+	}
+
+	if nested {
+		s.query += "("
+	}
+	for i, cond := range conds {
+		if i > 0 {
+			s.query += " " + g.andOr + " "
+		}
+		cond.buildSqlCondition(s, true)
+	}
+	if nested {
+		s.query += ")"
+	}
+}
+
+func (g *sqlConditionGroup) emptySqlCondition() bool {
+	for _, cond := range g.conds {
+		if !sqlConditionEmpty(cond) {
+			return false
+		}
+	}
+
+	return true
+}
+
+type sqlConditionNot struct {
+	cond SqlCondition
+}
+
+func Not(cond SqlCondition) SqlCondition {
+	return &sqlConditionNot{
+		cond: cond,
+	}
+}
+
+func (n *sqlConditionNot) buildSqlCondition(s *SqlQueryBuilder, nested bool) {
+	s.query += "NOT ("
+	n.cond.buildSqlCondition(s, false)
+	s.query += ")"
+}
+
+func (n *sqlConditionNot) emptySqlCondition() bool {
+	return sqlConditionEmpty(n.cond)
+}
+
+func sqlConditionEmpty(cond SqlCondition) bool {
+	return cond == nil || cond.emptySqlCondition()
+}
+
+type sqlConditionExists struct {
+	not bool
+	sub *SqlQueryBuilder
+}
+
+// Exists is EXISTS (sub), the args of sub are merged in place
+func Exists(sub *SqlQueryBuilder) SqlCondition {
+	return &sqlConditionExists{
+		sub: sub,
+	}
+}
+
+func NotExists(sub *SqlQueryBuilder) SqlCondition {
+	return &sqlConditionExists{
+		not: true,
+		sub: sub,
+	}
+}
+
+func (e *sqlConditionExists) buildSqlCondition(s *SqlQueryBuilder, nested bool) {
+	if e.not {
+		s.query += "NOT "
+	}
+	s.query += "EXISTS "
+	s.appendSubquery(e.sub)
+}
+
+func (e *sqlConditionExists) emptySqlCondition() bool {
+	return e.sub == nil
+}
@@ -32,9 +32,23 @@ type SqlUpdateColumn struct {
 	NoBind bool
 }
 
+// SqlInsertedValue as SqlUpdateColumn.Value in OnDuplicateKeyUpdate updates the column to the value it would have been inserted with
+type SqlInsertedValue struct{}
+
 type SqlQueryBuilder struct {
+	tableName string
+
 	query string
 	args  []interface{}
+
+	// column of each arg, "" if unknown
+	argColumns    []string
+	insertColumns []string
+}
+
+// TableName returns the table of the last Insert, Delete, Update or Select
+func (s *SqlQueryBuilder) TableName() string {
+	return s.tableName
 }
 
 func (s *SqlQueryBuilder) Query() string {
@@ -45,8 +59,28 @@ func (s *SqlQueryBuilder) Args() []interface{} {
 	return s.args
 }
 
-func (s *SqlQueryBuilder) Insert(tableName string, colNames ...string) *SqlQueryBuilder {
+// ArgColumns returns the column each arg is bound to, "" for args not bound to a column such as LIMIT
+func (s *SqlQueryBuilder) ArgColumns() []string {
+	return s.argColumns
+}
+
+func (s *SqlQueryBuilder) reset(tableName string) {
+	s.tableName = tableName
 	s.args = nil
+	s.argColumns = nil
+	s.insertColumns = nil
+}
+
+func (s *SqlQueryBuilder) appendArgs(column string, args ...interface{}) {
+	s.args = append(s.args, args...)
+	for range args {
+		s.argColumns = append(s.argColumns, column)
+	}
+}
+
+func (s *SqlQueryBuilder) Insert(tableName string, colNames ...string) *SqlQueryBuilder {
+	s.reset(tableName)
+	s.insertColumns = colNames
 
 	s.query = "INSERT INTO " + tableName + " ("
 	s.query += strings.Join(colNames, ", ") + ")"
@@ -71,7 +105,7 @@ func (s *SqlQueryBuilder) Values(colsValues ...[]interface{}) *SqlQueryBuilder {
 }
 
 func (s *SqlQueryBuilder) Delete(tableName string) *SqlQueryBuilder {
-	s.args = nil
+	s.reset(tableName)
 
 	s.query = "DELETE FROM " + tableName
 
@@ -79,7 +113,7 @@ func (s *SqlQueryBuilder) Delete(tableName string) *SqlQueryBuilder {
 }
 
 func (s *SqlQueryBuilder) Update(tableName string) *SqlQueryBuilder {
-	s.args = nil
+	s.reset(tableName)
 
 	s.query = "UPDATE " + tableName
 
@@ -92,27 +126,64 @@ func (s *SqlQueryBuilder) Set(updateColumns []*SqlUpdateColumn) *SqlQueryBuilder
 	}
 
 	s.query += " SET "
+	s.buildUpdateColumns("", updateColumns)
+
+	return s
+}
+
+// OnDuplicateKeyUpdate adds ON DUPLICATE KEY UPDATE after Values, columns valued SqlInsertedValue{} are rendered
+// as VALUES(col), or as rowAlias.col with the row alias declared if rowAlias is not "", which needs MySQL 8.0.19+
+func (s *SqlQueryBuilder) OnDuplicateKeyUpdate(rowAlias string, updateColumns []*SqlUpdateColumn) *SqlQueryBuilder {
+	if len(updateColumns) == 0 {
+		return s
+	}
+
+	if rowAlias != "" {
+		s.query += " AS " + rowAlias
+	}
+	s.query += " ON DUPLICATE KEY UPDATE "
+	s.buildUpdateColumns(rowAlias, updateColumns)
+
+	return s
+}
+
+func (s *SqlQueryBuilder) buildUpdateColumns(rowAlias string, updateColumns []*SqlUpdateColumn) {
 	for _, column := range updateColumns {
-		if column.NoBind {
+		if _, ok := column.Value.(SqlInsertedValue); ok {
+			if rowAlias != "" {
+				s.query += column.Name + " = " + rowAlias + "." + column.Name + ", "
+			} else {
+				s.query += column.Name + " = VALUES(" + column.Name + "), "
+			}
+		} else if column.NoBind {
 			s.query += column.Name + " = " + fmt.Sprint(column.Value) + ", "
 		} else {
 			s.query += column.Name + " = ?, "
-			s.args = append(s.args, column.Value)
+			s.appendArgs(column.Name, column.Value)
 		}
 	}
 	s.query = s.query[0 : len(s.query)-2]
-
-	return s
 }
 
 func (s *SqlQueryBuilder) Select(what, tableName string) *SqlQueryBuilder {
-	s.args = nil
+	s.reset(tableName)
 
 	s.query = "SELECT " + what + " FROM " + tableName
 
 	return s
 }
 
+// SelectFrom selects from the derived table built by sub, its args come before the ones of following clauses
+func (s *SqlQueryBuilder) SelectFrom(what string, sub *SqlQueryBuilder, alias string) *SqlQueryBuilder {
+	s.reset(sub.TableName())
+
+	s.query = "SELECT " + what + " FROM "
+	s.appendSubquery(sub)
+	s.query += " AS " + alias
+
+	return s
+}
+
 func (s *SqlQueryBuilder) WhereConditionAnd(condItems ...*SqlColQueryItem) *SqlQueryBuilder {
 	if len(condItems) == 0 {
 		return s
@@ -137,6 +208,51 @@ func (s *SqlQueryBuilder) WhereConditionOr(condItems ...*SqlColQueryItem) *SqlQu
 	return s
 }
 
+// Join adds an INNER JOIN of tableName, alias and on are omitted if empty.
+// Joins are appended in call order, so call them after Select or Update and before Set and Where,
+// then the args of on come before the ones of the following clauses as in the query.
+func (s *SqlQueryBuilder) Join(tableName, alias string, on SqlCondition) *SqlQueryBuilder {
+	return s.join("INNER JOIN", tableName, alias, on)
+}
+
+func (s *SqlQueryBuilder) LeftJoin(tableName, alias string, on SqlCondition) *SqlQueryBuilder {
+	return s.join("LEFT JOIN", tableName, alias, on)
+}
+
+func (s *SqlQueryBuilder) RightJoin(tableName, alias string, on SqlCondition) *SqlQueryBuilder {
+	return s.join("RIGHT JOIN", tableName, alias, on)
+}
+
+func (s *SqlQueryBuilder) CrossJoin(tableName, alias string) *SqlQueryBuilder {
+	return s.join("CROSS JOIN", tableName, alias, nil)
+}
+
+func (s *SqlQueryBuilder) join(joinType, tableName, alias string, on SqlCondition) *SqlQueryBuilder {
+	s.query += " " + joinType + " " + tableName
+	if alias != "" {
+		s.query += " AS " + alias
+	}
+
+	if !sqlConditionEmpty(on) {
+		s.query += " ON "
+		on.buildSqlCondition(s, false)
+	}
+
+	return s
+}
+
+// Where adds cond as the WHERE clause, nothing is added if cond is nil or empty
+func (s *SqlQueryBuilder) Where(cond SqlCondition) *SqlQueryBuilder {
+	if sqlConditionEmpty(cond) {
+		return s
+	}
+
+	s.query += " WHERE "
+	cond.buildSqlCondition(s, false)
+
+	return s
+}
+
 func (s *SqlQueryBuilder) OrderBy(orderBy string) *SqlQueryBuilder {
 	if orderBy != "" {
 		s.query += " ORDER BY " + orderBy
@@ -177,6 +293,18 @@ func (s *SqlQueryBuilder) HavingConditionOr(condItems ...*SqlColQueryItem) *SqlQ
 	return s
 }
 
+// Having adds cond as the HAVING clause, nothing is added if cond is nil or empty
+func (s *SqlQueryBuilder) Having(cond SqlCondition) *SqlQueryBuilder {
+	if sqlConditionEmpty(cond) {
+		return s
+	}
+
+	s.query += " HAVING "
+	cond.buildSqlCondition(s, false)
+
+	return s
+}
+
 func (s *SqlQueryBuilder) Limit(offset, cnt int64) *SqlQueryBuilder {
 	if cnt <= 0 {
 		return s
@@ -184,13 +312,13 @@ func (s *SqlQueryBuilder) Limit(offset, cnt int64) *SqlQueryBuilder {
 
 	if offset < 0 {
 		s.query += " LIMIT ?"
-		s.args = append(s.args, cnt)
+		s.appendArgs("", cnt)
 
 		return s
 	}
 
 	s.query += " LIMIT ?, ?"
-	s.args = append(s.args, offset, cnt)
+	s.appendArgs("", offset, cnt)
 
 	return s
 }
@@ -205,11 +333,26 @@ func (s *SqlQueryBuilder) buildColValues(colValues []interface{}) {
 
 	for i := 0; i < l; i++ {
 		s.query += "?, "
-		s.args = append(s.args, colValues[i])
+		s.appendArgs(s.insertColumn(i), colValues[i])
 	}
 
 	s.query += "?)"
-	s.args = append(s.args, colValues[l])
+	s.appendArgs(s.insertColumn(l), colValues[l])
+}
+
+// appendSubquery appends the parenthesized query of sub and its args
+func (s *SqlQueryBuilder) appendSubquery(sub *SqlQueryBuilder) {
+	s.query += "(" + sub.query + ")"
+	s.args = append(s.args, sub.args...)
+	s.argColumns = append(s.argColumns, sub.argColumns...)
+}
+
+func (s *SqlQueryBuilder) insertColumn(i int) string {
+	if i < len(s.insertColumns) {
+		return s.insertColumns[i]
+	}
+
+	return ""
 }
 
 func (s *SqlQueryBuilder) buildWhereCondition(andOr string, condItems ...*SqlColQueryItem) {
@@ -228,11 +371,14 @@ func (s *SqlQueryBuilder) buildWhereCondition(andOr string, condItems ...*SqlCol
 func (s *SqlQueryBuilder) buildCondition(condItem *SqlColQueryItem) {
 	switch condItem.Condition {
 	case SqlCondEqual, SqlCondNotEqual, SqlCondLess, SqlCondLessEqual, SqlCondGreater, SqlCondGreaterEqual:
-		if condItem.NoBind {
+		if sub, ok := condItem.Value.(*SqlQueryBuilder); ok {
+			s.query += condItem.Name + " " + condItem.Condition + " "
+			s.appendSubquery(sub)
+		} else if condItem.NoBind {
 			s.query += fmt.Sprintf("%s %s %s", condItem.Name, condItem.Condition, fmt.Sprint(condItem.Value))
 		} else {
 			s.query += fmt.Sprintf("%s %s ?", condItem.Name, condItem.Condition)
-			s.args = append(s.args, condItem.Value)
+			s.appendArgs(condItem.Name, condItem.Value)
 		}
 	case SqlCondIn:
 		s.buildConditionInOrNotIn(condItem, "IN")
@@ -243,7 +389,7 @@ func (s *SqlQueryBuilder) buildCondition(condItem *SqlColQueryItem) {
 			s.query += fmt.Sprintf("%s LIKE %s", condItem.Name, fmt.Sprint(condItem.Value))
 		} else {
 			s.query += fmt.Sprintf("%s LIKE ?", condItem.Name)
-			s.args = append(s.args, condItem.Value)
+			s.appendArgs(condItem.Name, condItem.Value)
 		}
 	case SqlCondBetween:
 		rev := reflect.ValueOf(condItem.Value)
@@ -252,15 +398,27 @@ func (s *SqlQueryBuilder) buildCondition(condItem *SqlColQueryItem) {
 				condItem.Name, fmt.Sprint(rev.Index(0).Interface()), fmt.Sprint(rev.Index(1).Interface()))
 		} else {
 			s.query += fmt.Sprintf("%s BETWEEN ? AND ?", condItem.Name)
-			s.args = append(s.args, rev.Index(0).Interface(), rev.Index(1).Interface())
+			s.appendArgs(condItem.Name, rev.Index(0).Interface(), rev.Index(1).Interface())
 		}
 	}
 }
 
 func (s *SqlQueryBuilder) buildConditionInOrNotIn(condItem *SqlColQueryItem, inOrNotIn string) {
+	if sub, ok := condItem.Value.(*SqlQueryBuilder); ok {
+		s.query += condItem.Name + " " + inOrNotIn + " "
+		s.appendSubquery(sub)
+		return
+	}
+
 	rev := reflect.ValueOf(condItem.Value)
 	l := rev.Len() - 1
 	if l == -1 {
+		// IN () matches no row and NOT IN () every row, MySQL rejects both as syntax errors
+		if inOrNotIn == "IN" {
+			s.query += "1 = 0"
+		} else {
+			s.query += "1 = 1"
+		}
 		return
 	}
 
@@ -271,6 +429,6 @@ func (s *SqlQueryBuilder) buildConditionInOrNotIn(condItem *SqlColQueryItem, inO
 	s.query += "?)"
 
 	for i := 0; i < rev.Len(); i++ {
-		s.args = append(s.args, rev.Index(i).Interface())
+		s.appendArgs(condItem.Name, rev.Index(i).Interface())
 	}
 }
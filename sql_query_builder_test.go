@@ -91,6 +91,192 @@ func TestSQBLimit(t *testing.T) {
 	printQueryAndArgs()
 }
 
+func TestSQBArgColumns(t *testing.T) {
+	sqb := new(SqlQueryBuilder)
+	sqb.Select("*", TableName).
+		WhereConditionAnd(
+			&SqlColQueryItem{"name", SqlCondEqual, "a", false},
+			&SqlColQueryItem{"id", SqlCondIn, []int{1, 2}, false},
+		).
+		Limit(0, 10)
+
+	expect := []string{"name", "id", "id", "", ""}
+	if fmt.Sprint(sqb.ArgColumns()) != fmt.Sprint(expect) {
+		t.Error("arg columns error", sqb.ArgColumns())
+	}
+
+	sqb.Insert(TableName, "name", "password").
+		Values([]interface{}{"a", "pa"}, []interface{}{"b", "pb"})
+	expect = []string{"name", "password", "name", "password"}
+	if fmt.Sprint(sqb.ArgColumns()) != fmt.Sprint(expect) {
+		t.Error("insert arg columns error", sqb.ArgColumns())
+	}
+}
+
+func TestSQBConditionTree(t *testing.T) {
+	sqb := new(SqlQueryBuilder)
+	sqb.Select("name, count(*)", TableName).
+		Where(And(
+			&SqlColQueryItem{"a", SqlCondEqual, 1, false},
+			Or(
+				&SqlColQueryItem{"b", SqlCondEqual, 2, false},
+				&SqlColQueryItem{"c", SqlCondIn, []int{3, 4}, false},
+				Not(And(
+					&SqlColQueryItem{"d", SqlCondLike, "x%", false},
+					&SqlColQueryItem{"e", SqlCondBetween, []int{5, 6}, false},
+				)),
+			),
+			Or(),
+			nil,
+		)).
+		GroupBy("name").
+		Having(Or(
+			&SqlColQueryItem{"count(*)", SqlCondGreater, 7, false},
+		)).
+		Limit(0, 10)
+
+	expect := "SELECT name, count(*) FROM demo WHERE a = ? AND (b = ? OR c IN (?, ?) OR NOT (d LIKE ? AND e BETWEEN ? AND ?))" +
+		" GROUP BY name HAVING count(*) > ? LIMIT ?, ?"
+	if sqb.Query() != expect {
+		t.Error("query error", sqb.Query())
+	}
+	if fmt.Sprint(sqb.Args()) != "[1 2 3 4 x% 5 6 7 0 10]" {
+		t.Error("args error", sqb.Args())
+	}
+	if fmt.Sprint(sqb.ArgColumns()) != fmt.Sprint([]string{"a", "b", "c", "c", "d", "e", "e", "count(*)", "", ""}) {
+		t.Error("arg columns error", sqb.ArgColumns())
+	}
+
+	sqb.Select("*", TableName).Where(And(
+		&SqlColQueryItem{"id", SqlCondIn, []int{}, false},
+		&SqlColQueryItem{"a", SqlCondEqual, 1, false},
+		&SqlColQueryItem{"b", SqlCondNotIn, []int64(nil), false},
+	))
+	if sqb.Query() != "SELECT * FROM demo WHERE 1 = 0 AND a = ? AND 1 = 1" {
+		t.Error("empty in condition error", sqb.Query())
+	}
+
+	sqb.Delete(TableName).Where(And(&SqlColQueryItem{"id", SqlCondIn, []int64{}, false}))
+	if sqb.Query() != "DELETE FROM demo WHERE 1 = 0" {
+		t.Error("empty in condition should match no row", sqb.Query())
+	}
+
+	sqb.Select("*", TableName).Where(And(Or(), nil))
+	if sqb.Query() != "SELECT * FROM demo" {
+		t.Error("empty condition error", sqb.Query())
+	}
+
+	params := &SqlQueryParams{
+		CondItems: []*SqlColQueryItem{{"a", SqlCondEqual, 1, false}},
+		Condition: Or(&SqlColQueryItem{"b", SqlCondEqual, 2, false}, &SqlColQueryItem{"c", SqlCondEqual, 3, false}),
+	}
+	sqb.Select("*", TableName).Where(params.condition())
+	if sqb.Query() != "SELECT * FROM demo WHERE a = ? AND (b = ? OR c = ?)" {
+		t.Error("params condition error", sqb.Query())
+	}
+}
+
+func TestSQBJoin(t *testing.T) {
+	sqb := new(SqlQueryBuilder)
+	sqb.Select("d.name, u.email", "demo AS d").
+		Join("user", "u", And(
+			&SqlColQueryItem{"u.demo_id", SqlCondEqual, "d.id", true},
+			&SqlColQueryItem{"u.status", SqlCondEqual, 1, false},
+		)).
+		LeftJoin("profile", "p", &SqlColQueryItem{"p.user_id", SqlCondEqual, "u.id", true}).
+		RightJoin("team", "", &SqlColQueryItem{"team.id", SqlCondIn, []int{2, 3}, false}).
+		CrossJoin("region", "r").
+		Where(&SqlColQueryItem{"d.status", SqlCondEqual, 4, false}).
+		Limit(-1, 10)
+
+	expect := "SELECT d.name, u.email FROM demo AS d" +
+		" INNER JOIN user AS u ON u.demo_id = d.id AND u.status = ?" +
+		" LEFT JOIN profile AS p ON p.user_id = u.id" +
+		" RIGHT JOIN team ON team.id IN (?, ?)" +
+		" CROSS JOIN region AS r" +
+		" WHERE d.status = ? LIMIT ?"
+	if sqb.Query() != expect {
+		t.Error("query error", sqb.Query())
+	}
+	if fmt.Sprint(sqb.Args()) != "[1 2 3 4 10]" {
+		t.Error("args error", sqb.Args())
+	}
+}
+
+func TestSQBSubquery(t *testing.T) {
+	inSub := new(SqlQueryBuilder)
+	inSub.Select("demo_id", "user").Where(&SqlColQueryItem{"status", SqlCondEqual, 1, false})
+
+	maxSub := new(SqlQueryBuilder)
+	maxSub.Select("max(id)", "demo").Where(&SqlColQueryItem{"status", SqlCondEqual, 2, false})
+
+	existsSub := new(SqlQueryBuilder)
+	existsSub.Select("1", "profile AS p").Where(And(
+		&SqlColQueryItem{"p.demo_id", SqlCondEqual, "t.id", true},
+		&SqlColQueryItem{"p.name", SqlCondEqual, "a", false},
+	))
+
+	from := new(SqlQueryBuilder)
+	from.Select("id, name", "demo").Where(&SqlColQueryItem{"add_time", SqlCondGreater, "2016-06-23", false})
+
+	sqb := new(SqlQueryBuilder)
+	sqb.SelectFrom("t.id", from, "t").
+		Where(And(
+			&SqlColQueryItem{"t.id", SqlCondIn, inSub, false},
+			&SqlColQueryItem{"t.id", SqlCondNotIn, []int{3}, false},
+			&SqlColQueryItem{"t.id", SqlCondLess, maxSub, false},
+			Or(Exists(existsSub), NotExists(existsSub)),
+		)).
+		Limit(0, 10)
+
+	expect := "SELECT t.id FROM (SELECT id, name FROM demo WHERE add_time > ?) AS t" +
+		" WHERE t.id IN (SELECT demo_id FROM user WHERE status = ?) AND t.id NOT IN (?)" +
+		" AND t.id < (SELECT max(id) FROM demo WHERE status = ?)" +
+		" AND (EXISTS (SELECT 1 FROM profile AS p WHERE p.demo_id = t.id AND p.name = ?)" +
+		" OR NOT EXISTS (SELECT 1 FROM profile AS p WHERE p.demo_id = t.id AND p.name = ?))" +
+		" LIMIT ?, ?"
+	if sqb.Query() != expect {
+		t.Error("query error", sqb.Query())
+	}
+	if fmt.Sprint(sqb.Args()) != "[2016-06-23 1 3 2 a a 0 10]" {
+		t.Error("args error", sqb.Args())
+	}
+	if fmt.Sprint(sqb.ArgColumns()) != fmt.Sprint([]string{"add_time", "status", "t.id", "status", "p.name", "p.name", "", ""}) {
+		t.Error("arg columns error", sqb.ArgColumns())
+	}
+	if sqb.TableName() != "demo" {
+		t.Error("table name error", sqb.TableName())
+	}
+}
+
+func TestSQBOnDuplicateKeyUpdate(t *testing.T) {
+	updateColumns := []*SqlUpdateColumn{
+		{Name: "name", Value: SqlInsertedValue{}},
+		{Name: "status", Value: "status + 1", NoBind: true},
+		{Name: "edit_time", Value: "2016-06-23 09:00:00"},
+	}
+
+	sqb := new(SqlQueryBuilder)
+	sqb.Insert(TableName, "id", "name").
+		Values([]interface{}{1, "a"}, []interface{}{2, "b"}).
+		OnDuplicateKeyUpdate("", updateColumns)
+	expect := "INSERT INTO demo (id, name) VALUES (?, ?), (?, ?)" +
+		" ON DUPLICATE KEY UPDATE name = VALUES(name), status = status + 1, edit_time = ?"
+	if sqb.Query() != expect {
+		t.Error("query error", sqb.Query())
+	}
+	if fmt.Sprint(sqb.Args()) != "[1 a 2 b 2016-06-23 09:00:00]" {
+		t.Error("args error", sqb.Args())
+	}
+
+	sqb.Insert(TableName, "id", "name").
+		Values([]interface{}{1, "a"}).
+		OnDuplicateKeyUpdate("new", updateColumns[:1])
+	if sqb.Query() != "INSERT INTO demo (id, name) VALUES (?, ?) AS new ON DUPLICATE KEY UPDATE name = new.name" {
+		t.Error("row alias query error", sqb.Query())
+	}
+}
+
 func printQueryAndArgs() {
 	fmt.Println(sqb.Query(), sqb.Args())
 }
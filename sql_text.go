@@ -0,0 +1,134 @@
+package mysql
+
+import (
+	"strings"
+)
+
+type sqlTokenKind int
+
+const (
+	sqlTokenPunct sqlTokenKind = iota
+	sqlTokenSpace
+	sqlTokenWord
+	sqlTokenNumber
+	sqlTokenString
+	sqlTokenQuotedIdent
+	sqlTokenComment
+	sqlTokenPlaceholder
+)
+
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+}
+
+// scanSql splits query into tokens the way the MySQL lexer sees quotes and comments,
+// concatenating the text of all tokens gives query back
+func scanSql(query string) []*sqlToken {
+	var tokens []*sqlToken
+
+	for i := 0; i < len(query); {
+		kind, end := sqlTokenPunct, i+1
+
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			kind, end = sqlTokenString, scanSqlQuoted(query, i, c, true)
+		case c == '`':
+			kind, end = sqlTokenQuotedIdent, scanSqlQuoted(query, i, c, false)
+		case c == '#':
+			kind, end = sqlTokenComment, scanSqlLineEnd(query, i)
+		case c == '-' && strings.HasPrefix(query[i:], "--") && (i+2 == len(query) || isSqlSpace(query[i+2])):
+			kind, end = sqlTokenComment, scanSqlLineEnd(query, i)
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			kind, end = sqlTokenComment, len(query)
+			if j := strings.Index(query[i+2:], "*/"); j >= 0 {
+				end = i + 2 + j + 2
+			}
+		case c == '?':
+			kind = sqlTokenPlaceholder
+		case isSqlSpace(c):
+			kind = sqlTokenSpace
+			for end < len(query) && isSqlSpace(query[end]) {
+				end++
+			}
+		case isSqlDigit(c) || (c == '.' && i+1 < len(query) && isSqlDigit(query[i+1])):
+			kind = sqlTokenNumber
+			for end < len(query) && (isSqlWordChar(query[end]) || query[end] == '.' ||
+				((query[end] == '+' || query[end] == '-') && (query[end-1] == 'e' || query[end-1] == 'E'))) {
+				end++
+			}
+		case isSqlWordChar(c):
+			kind = sqlTokenWord
+			for end < len(query) && isSqlWordChar(query[end]) {
+				end++
+			}
+		}
+
+		tokens = append(tokens, &sqlToken{
+			kind: kind,
+			text: query[i:end],
+		})
+		i = end
+	}
+
+	return tokens
+}
+
+// scanSqlQuoted returns the end of the quoted text starting at i, a doubled quote stays inside,
+// so does a quote escaped by backslash when backslash is true
+func scanSqlQuoted(query string, i int, quote byte, backslash bool) int {
+	for j := i + 1; j < len(query); j++ {
+		switch query[j] {
+		case '\\':
+			if backslash {
+				j++
+			}
+		case quote:
+			if j+1 < len(query) && query[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+
+	return len(query)
+}
+
+func scanSqlLineEnd(query string, i int) int {
+	j := strings.IndexByte(query[i:], '\n')
+	if j < 0 {
+		return len(query)
+	}
+
+	return i + j
+}
+
+func isSqlSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isSqlDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isSqlWordChar(c byte) bool {
+	return isSqlDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_' || c == '$' || c >= 0x80
+}
+
+// SanitizeQuery replaces string and number literals in query by ?, leaving the placeholders as they are
+func SanitizeQuery(query string) string {
+	var sb strings.Builder
+
+	for _, token := range scanSql(query) {
+		switch token.kind {
+		case sqlTokenString, sqlTokenNumber:
+			sb.WriteByte('?')
+		default:
+			sb.WriteString(token.text)
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,24 @@
+package mysql
+
+import (
+	"testing"
+)
+
+func TestSanitizeQuery(t *testing.T) {
+	cases := []struct {
+		query  string
+		expect string
+	}{
+		{"SELECT * FROM demo WHERE id = 1", "SELECT * FROM demo WHERE id = ?"},
+		{"SELECT * FROM demo2 WHERE name = 'a''b' AND x = \"c\\\"d\" AND id = ?", "SELECT * FROM demo2 WHERE name = ? AND x = ? AND id = ?"},
+		{"SELECT `1a`, t1.c2 FROM t1 WHERE v IN (1.5, -2, 3e+10, 0x1F)", "SELECT `1a`, t1.c2 FROM t1 WHERE v IN (?, -?, ?, ?)"},
+		{"/* 'keep' 1 */ SELECT 1 -- 'comment' 2\n# 3\n", "/* 'keep' 1 */ SELECT ? -- 'comment' 2\n# 3\n"},
+		{"SELECT 'unclosed", "SELECT ?"},
+	}
+
+	for _, c := range cases {
+		if got := SanitizeQuery(c.query); got != c.expect {
+			t.Errorf("SanitizeQuery(%q) = %q, expect %q", c.query, got, c.expect)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"github.com/goinbox/golog"
+	"github.com/goinbox/pcontext"
+)
+
+// stmtStats is the outcome of one statement, reported by statementDone once the statement is finished,
+// for Query and QueryRow that is when the rows are closed
+type stmtStats struct {
+	config *Config
+	span   Span
+
+	op    Operation
+	table string
+	query string
+	args  []interface{}
+
+	start    time.Time
+	duration time.Duration
+
+	// rows affected for OpExec, rows read for OpQuery and OpQueryRow
+	rowsAffected int64
+	lastInsertID int64
+
+	err error
+}
+
+// newStmtStats starts the stats of a statement running on the DB of config
+func (c *Client) newStmtStats(ctx pcontext.Context,
+	op Operation, query string, args []interface{}, config *Config) *stmtStats {
+	stats := &stmtStats{
+		config: config,
+
+		op:    op,
+		table: TableFromContext(ctx),
+		query: query,
+		args:  args,
+		start: time.Now(),
+	}
+	stats.span = c.startStmtSpan(ctx, stats)
+
+	return stats
+}
+
+func (s *stmtStats) done(err error) *stmtStats {
+	s.duration = time.Since(s.start)
+	s.err = err
+
+	return s
+}
+
+// observeRows returns the context passed to the driver, so that stats is reported when the rows are closed
+func (c *Client) observeRows(ctx pcontext.Context, stats *stmtStats) context.Context {
+	return withRowsObserver(ctx, func(rows int64, err error) {
+		stats.rowsAffected = rows
+		c.statementDone(ctx, stats.done(err))
+	})
+}
+
+func (c *Client) statementDone(ctx pcontext.Context, stats *stmtStats) {
+	c.logStatement(ctx, stats)
+	c.logSlowQuery(ctx, stats)
+	c.observeMetrics(stats)
+	c.observeDigest(stats)
+	endStmtSpan(stats)
+}
+
+func (c *Client) logSlowQuery(ctx pcontext.Context, stats *stmtStats) {
+	threshold := c.config.SlowQueryThreshold
+	if threshold <= 0 || stats.duration < threshold {
+		return
+	}
+
+	fields := c.sqlLogFields(ctx, stats.query, stats.args)
+	ctx.Logger().Warning("slow sql", append(fields, &golog.Field{
+		Key:   c.config.LogFieldKeyDuration,
+		Value: stats.duration.String(),
+	}, &golog.Field{
+		Key:   c.config.LogFieldKeyRows,
+		Value: stats.rowsAffected,
+	})...)
+}
@@ -0,0 +1,178 @@
+package mysql
+
+import (
+	"context"
+	"sync"
+
+	"github.com/goinbox/pcontext"
+)
+
+const (
+	SpanAttrDBSystem     = "db.system"
+	SpanAttrDBName       = "db.name"
+	SpanAttrPeerAddress  = "net.peer.address"
+	SpanAttrOperation    = "db.operation"
+	SpanAttrStatement    = "db.statement"
+	SpanAttrRowsAffected = "db.rows_affected"
+	SpanAttrTxOutcome    = "db.transaction.outcome"
+
+	SpanNameTransaction = "mysql.transaction"
+)
+
+type SpanAttribute struct {
+	Key   string
+	Value interface{}
+}
+
+type Span interface {
+	SetAttributes(attrs ...*SpanAttribute)
+	RecordError(err error)
+	End()
+}
+
+// Tracer is the small part of a tracing API Client needs, an OpenTelemetry adapter starts
+// the span as a child of parent when it is not nil, otherwise of the span found in ctx
+type Tracer interface {
+	StartSpan(ctx context.Context, name string, parent Span) Span
+}
+
+func (c *Client) startSpan(ctx pcontext.Context, name string, config *Config, parent Span) Span {
+	tracer := c.config.Tracer
+	if tracer == nil {
+		return nil
+	}
+
+	span := tracer.StartSpan(ctx, name, parent)
+	span.SetAttributes(&SpanAttribute{
+		Key:   SpanAttrDBSystem,
+		Value: "mysql",
+	}, &SpanAttribute{
+		Key:   SpanAttrDBName,
+		Value: config.DBName,
+	}, &SpanAttribute{
+		Key:   SpanAttrPeerAddress,
+		Value: config.Addr,
+	})
+
+	return span
+}
+
+// startStmtSpan starts the span of a statement, inside a transaction it is a child of the transaction span
+func (c *Client) startStmtSpan(ctx pcontext.Context, stats *stmtStats) Span {
+	span := c.startSpan(ctx, "mysql."+string(stats.op), stats.config, c.txSpan)
+	if span == nil {
+		return nil
+	}
+
+	span.SetAttributes(&SpanAttribute{
+		Key:   SpanAttrOperation,
+		Value: string(stats.op),
+	}, &SpanAttribute{
+		Key:   SpanAttrStatement,
+		Value: SanitizeQuery(stats.query),
+	})
+
+	return span
+}
+
+func endStmtSpan(stats *stmtStats) {
+	if stats.span == nil {
+		return
+	}
+
+	stats.span.SetAttributes(&SpanAttribute{
+		Key:   SpanAttrRowsAffected,
+		Value: stats.rowsAffected,
+	})
+	endSpan(stats.span, stats.err)
+}
+
+func endSpan(span Span, err error) {
+	if span == nil {
+		return
+	}
+
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// endTxSpan ends the transaction span with outcome commit or rollback
+func (c *Client) endTxSpan(outcome string, err error) {
+	if c.txSpan == nil {
+		return
+	}
+
+	c.txSpan.SetAttributes(&SpanAttribute{
+		Key:   SpanAttrTxOutcome,
+		Value: outcome,
+	})
+	endSpan(c.txSpan, err)
+}
+
+type MemorySpan struct {
+	Name       string
+	Parent     *MemorySpan
+	Attributes map[string]interface{}
+	Errors     []error
+	Ended      bool
+
+	tracer *MemoryTracer
+}
+
+func (s *MemorySpan) SetAttributes(attrs ...*SpanAttribute) {
+	s.tracer.lock.Lock()
+	for _, attr := range attrs {
+		s.Attributes[attr.Key] = attr.Value
+	}
+	s.tracer.lock.Unlock()
+}
+
+func (s *MemorySpan) RecordError(err error) {
+	s.tracer.lock.Lock()
+	s.Errors = append(s.Errors, err)
+	s.tracer.lock.Unlock()
+}
+
+func (s *MemorySpan) End() {
+	s.tracer.lock.Lock()
+	s.Ended = true
+	s.tracer.lock.Unlock()
+}
+
+// MemoryTracer records spans in memory, it is meant for tests
+type MemoryTracer struct {
+	lock  sync.Mutex
+	spans []*MemorySpan
+}
+
+func NewMemoryTracer() *MemoryTracer {
+	return new(MemoryTracer)
+}
+
+func (t *MemoryTracer) StartSpan(ctx context.Context, name string, parent Span) Span {
+	span := &MemorySpan{
+		Name:       name,
+		Attributes: map[string]interface{}{},
+
+		tracer: t,
+	}
+	if p, ok := parent.(*MemorySpan); ok {
+		span.Parent = p
+	}
+
+	t.lock.Lock()
+	t.spans = append(t.spans, span)
+	t.lock.Unlock()
+
+	return span
+}
+
+// Spans returns the recorded spans in start order
+func (t *MemoryTracer) Spans() []*MemorySpan {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return append([]*MemorySpan(nil), t.spans...)
+}
@@ -0,0 +1,48 @@
+package mysql
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClientTracing(t *testing.T) {
+	tracer := NewMemoryTracer()
+	config := NewDefaultConfig("root", "123", "127.0.0.1", "gobox-demo", 3306)
+	config.Tracer = tracer
+	client := &Client{config: config}
+
+	txSpan := client.startSpan(ctx, SpanNameTransaction, config, nil)
+	tx := client.txClient(nil, 0)
+	tx.txSpan = txSpan
+
+	stats := tx.newStmtStats(ctx, OpExec, "UPDATE demo SET name = 'a' WHERE id = ?", []interface{}{1}, config)
+	stats.rowsAffected = 1
+	tx.statementDone(ctx, stats.done(nil))
+	tx.endTxSpan("rollback", errors.New("broken"))
+
+	spans := tracer.Spans()
+	if len(spans) != 2 {
+		t.Fatal("spans error", spans)
+	}
+
+	stmtSpan := spans[1]
+	if stmtSpan.Name != "mysql.exec" || stmtSpan.Parent != spans[0] || !stmtSpan.Ended {
+		t.Error("statement span error", stmtSpan)
+	}
+	expects := map[string]interface{}{
+		SpanAttrDBSystem:     "mysql",
+		SpanAttrDBName:       "gobox-demo",
+		SpanAttrPeerAddress:  "127.0.0.1:3306",
+		SpanAttrStatement:    "UPDATE demo SET name = ? WHERE id = ?",
+		SpanAttrRowsAffected: int64(1),
+	}
+	for key, value := range expects {
+		if stmtSpan.Attributes[key] != value {
+			t.Error("statement span attribute error", key, stmtSpan.Attributes[key])
+		}
+	}
+
+	if !spans[0].Ended || len(spans[0].Errors) != 1 || spans[0].Attributes[SpanAttrTxOutcome] != "rollback" {
+		t.Error("transaction span error", spans[0])
+	}
+}